@@ -0,0 +1,131 @@
+// Package cmd. checkIgnoreCmd explains why paths are skipped by run.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+	"github.com/seyedali-dev/treeclip/internal/traversal"
+	"github.com/spf13/cobra"
+)
+
+// checkIgnoreRoot is the traversal root the checked paths are resolved against.
+var checkIgnoreRoot string
+
+func init() {
+	checkIgnoreCmd.Flags().StringVarP(&checkIgnoreRoot, "root", "r", "", "Traversal root the paths belong to (default: current directory)")
+	checkIgnoreCmd.Flags().StringArrayVarP(&excludePatterns, "exclude", "e", []string{}, "Exclude patterns, as passed to run")
+	checkIgnoreCmd.Flags().StringArrayVarP(&includePatterns, "include", "i", []string{}, "Include patterns, as passed to run")
+	checkIgnoreCmd.Flags().BoolVar(&gitIgnoreEnabled, "gitignore", true, "Also honor .gitignore, .git/info/exclude and core.excludesFile")
+	checkIgnoreCmd.Flags().StringSliceVar(&profileNames, "profile", []string{}, "Default exclusion profiles, as passed to run")
+	checkIgnoreCmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "Disable default exclusions, as passed to run")
+
+	rootCmd.AddCommand(checkIgnoreCmd)
+}
+
+// checkIgnoreCmd prints the pattern, its source and the matching rule that exclude each given path.
+var checkIgnoreCmd = &cobra.Command{
+	Use:   "check-ignore <path...>",
+	Short: "Explain why paths are excluded from run's output",
+	Long: `Explain why paths are excluded from run's output.
+
+For every path, prints the deciding pattern, where it came from (flag, file and line, or the default exclusions
+of a profile) and the rule that matched (name, relative path, wildcard or parent dir).
+Pass the same --exclude/--include/--gitignore/--profile flags you give to run.
+
+Examples:
+  treeclip check-ignore node_modules/react/index.js
+  treeclip check-ignore -e "*.log" logs/app.log build/
+  treeclip check-ignore --root ~/project ~/project/vendor/x.go`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: registerCheckIgnoreCmd(),
+}
+
+// registerCheckIgnoreCmd handles the actual logic for explaining exclusions.
+func registerCheckIgnoreCmd() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var rootArgs []string
+		if checkIgnoreRoot != "" {
+			rootArgs = []string{checkIgnoreRoot}
+		}
+		rootDir, err := determineRootDir(rootArgs)
+		if err != nil {
+			return err
+		}
+
+		input := dirInput(rootDir)
+		profiles, err := activeProfiles(input)
+		if err != nil {
+			return err
+		}
+		opts, err := traversalOptions(input, profiles)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args {
+			rel, isDir, err := relativeToRoot(rootDir, arg)
+			if err != nil {
+				return err
+			}
+
+			match, err := traversal.Explain(rootDir, rel, isDir, opts)
+			if err != nil {
+				return err
+			}
+			printMatch(rel, match)
+		}
+		return nil
+	}
+}
+
+// relativeToRoot resolves a path argument against rootDir. A trailing slash marks a path that does not exist as a directory.
+func relativeToRoot(rootDir, arg string) (rel string, isDir bool, err error) {
+	absPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid path: %w", err)
+	}
+	rel, err = filepath.Rel(rootDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, fmt.Errorf("path %s is outside of %s (ノಠ益ಠ)ノ", arg, rootDir)
+	}
+
+	if info, statErr := os.Stat(absPath); statErr == nil {
+		isDir = info.IsDir()
+	} else {
+		isDir = strings.HasSuffix(arg, "/") || strings.HasSuffix(arg, string(filepath.Separator))
+	}
+	return filepath.ToSlash(rel), isDir, nil
+}
+
+// printMatch prints the outcome for a single path.
+func printMatch(rel string, match exclude.Match) {
+	if match.Excluded {
+		fmt.Printf("🚫  %s\n", rel)
+	} else {
+		fmt.Printf("✅  %s\n", rel)
+	}
+
+	if match.Rule == exclude.RuleNotIncluded {
+		fmt.Printf("    rule:    %s (no --include or %s pattern allows it)\n", match.Rule, exclude.IncludeFileName)
+		return
+	}
+	if match.Pattern == nil {
+		fmt.Println("    not matched by any pattern")
+		return
+	}
+
+	fmt.Printf("    pattern: %s\n", match.Pattern.Raw)
+	fmt.Printf("    source:  %s\n", match.Pattern.Source)
+	switch {
+	case match.Rule == exclude.RuleParentDir:
+		fmt.Printf("    rule:    %s (%s)\n", match.Rule, match.Path)
+	case match.Pattern.Negate:
+		fmt.Printf("    rule:    %s (re-included)\n", match.Rule)
+	default:
+		fmt.Printf("    rule:    %s\n", match.Rule)
+	}
+}
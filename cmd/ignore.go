@@ -0,0 +1,93 @@
+// Package cmd. ignoreCmd groups the commands working on .treeclipignore files.
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+	"github.com/seyedali-dev/treeclip/internal/traversal"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ignoreLintCmd.Flags().BoolVar(&gitIgnoreEnabled, "gitignore", true, "Also honor .gitignore, .git/info/exclude and core.excludesFile")
+	ignoreLintCmd.Flags().StringSliceVar(&profileNames, "profile", []string{}, "Default exclusion profiles, as passed to run")
+	ignoreLintCmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "Disable default exclusions, as passed to run")
+
+	ignoreCmd.AddCommand(ignoreLintCmd)
+	rootCmd.AddCommand(ignoreCmd)
+}
+
+// ignoreCmd is the parent of the .treeclipignore related commands.
+var ignoreCmd = &cobra.Command{
+	Use:   "ignore",
+	Short: "Work with .treeclipignore files",
+}
+
+// ignoreLintCmd reports problems in the .treeclipignore files of a tree.
+var ignoreLintCmd = &cobra.Command{
+	Use:   "lint [path | cwd if empty]",
+	Short: "Report invalid, unused and shadowed patterns in .treeclipignore files",
+	Long: `Report problems in every .treeclipignore file of a tree:
+
+  invalid             the pattern can't be parsed and is ignored
+  suspicious          e.g. "***.mod": a "**" that is not a whole segment matches like "*"
+  matches nothing     no file or folder in the tree matches the pattern
+  shadowed            earlier patterns (defaults, git rules, earlier lines) already decide everything it matches
+  duplicates default  the pattern is already part of the active default exclusion profiles
+
+Exits with an error when any issue is found. "treeclip run --strict" runs the same checks first.
+
+Examples:
+  treeclip ignore lint
+  treeclip ignore lint /path/to/dir --gitignore=false`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         registerIgnoreLintCmd(),
+}
+
+// registerIgnoreLintCmd handles the actual logic for linting ignore files.
+func registerIgnoreLintCmd() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		rootDir, err := determineRootDir(args)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("🔍  Linting %s files in %s ... (・_・ヾ\n", exclude.IgnoreFileName, rootDir)
+		input := dirInput(rootDir)
+		profiles, err := activeProfiles(input)
+		if err != nil {
+			return err
+		}
+		opts, err := traversalOptions(input, profiles)
+		if err != nil {
+			return err
+		}
+		if err := lintIgnoreFiles(input, opts); err != nil {
+			return err
+		}
+		fmt.Println("✅  No issues found! ヽ(•‿•)ノ")
+		return nil
+	}
+}
+
+// lintIgnoreFiles prints the issues of the .treeclipignore files in input, read with opts, and fails if there are any.
+func lintIgnoreFiles(input inputTree, opts traversal.Options) error {
+	issues, err := traversal.LintTree(input.Tree, opts)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	for _, issue := range issues {
+		if rel, err := filepath.Rel(input.dir, issue.Source.Name); err == nil {
+			issue.Source.Name = filepath.ToSlash(rel)
+		}
+		fmt.Printf("⚠️  %s\n", issue)
+	}
+	return fmt.Errorf("found %d issue(s) in %s files (；一_一)", len(issues), exclude.IgnoreFileName)
+}
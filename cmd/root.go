@@ -2,6 +2,7 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -12,8 +13,25 @@ var rootCmd = &cobra.Command{
 	Short: "treeclip – copy directory contents to clipboard and editor",
 }
 
+// exitPartial is the exit code of a run that completed but had to skip unreadable files, see --on-error.
+const exitPartial = 3
+
+// exitError makes Execute exit with a specific code instead of 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -2,15 +2,29 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	fileUtils "github.com/seyedali-dev/treeclip/pkg/utils"
 
+	"github.com/seyedali-dev/treeclip/internal/archive"
 	"github.com/seyedali-dev/treeclip/internal/clipboard"
 	"github.com/seyedali-dev/treeclip/internal/editor"
 	"github.com/seyedali-dev/treeclip/internal/exclude"
+	"github.com/seyedali-dev/treeclip/internal/git"
+	"github.com/seyedali-dev/treeclip/internal/output"
+	"github.com/seyedali-dev/treeclip/internal/snippet"
 	"github.com/seyedali-dev/treeclip/internal/traversal"
 	"github.com/spf13/cobra"
 )
@@ -20,28 +34,87 @@ const outputFile = "treeclip_temp.txt"
 
 var (
 	excludePatterns    []string
+	includePatterns    []string
 	clipboardEnabled   bool
 	showClipboardStats bool
 	editorEnabled      bool
 	deleteAfterEditor  bool
+	gitIgnoreEnabled   bool
+	strictIgnoreFiles  bool
+	profileNames       []string
+	noDefaultExcludes  bool
+	maxFileSize        string
+	maxTotalSize       string
+	skipGenerated      bool
+	generatedMarkers   []string
+	generatedLines     int
+	gitSelection       git.Selection
+	revision           string
+	modifiedWithin     string
+	modifiedSince      string
+	newerThan          string
+	readJobs           int
+	sortOrder          string
+	priorityPatterns   []string
+	followSymlinks     bool
+	readTimeout        time.Duration
+	onError            string
+	maxDepth           int
+	filesFromStdin     bool
+	filesFrom          string
+	outputFormat       string
 )
 
 func init() {
-	runCmd.Flags().StringSliceVarP(&excludePatterns, "exclude", "e", []string{}, "Exclude files/folders matching these patterns (can be used multiple times)")
+	addSelectionFlags(runCmd)
 	runCmd.Flags().BoolVarP(&clipboardEnabled, "clipboard", "c", true, "Copy output to clipboard")
 	runCmd.Flags().BoolVar(&showClipboardStats, "stats", false, "Show clipboard content statistics")
 	runCmd.Flags().BoolVarP(&editorEnabled, "editor", "o", false, "Open output file in the default text editor")
 	runCmd.Flags().BoolVarP(&deleteAfterEditor, "delete", "d", true, "Delete the output file after editor is closed")
+	runCmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "Skip the content of files larger than this, e.g. 512KB or 2MB (default: no limit)")
+	runCmd.Flags().StringVar(&maxTotalSize, "max-total-size", "", "Replace the content of files that would push the total of file contents over this size by a placeholder, e.g. 10MB (default: no limit)")
+	runCmd.Flags().StringVar(&sortOrder, "sort", string(traversal.SortPath), "Order of the files in the output: path, size, mtime (newest first), ext or dirs-first")
+	runCmd.Flags().StringArrayVarP(&priorityPatterns, "priority", "p", []string{}, "Output files matching these patterns first, in the given order (can be used multiple times)")
+	runCmd.Flags().StringVar(&outputFormat, "format", "text", "Output format: text (\"==> path\" headers) or md (Markdown, a fenced code block per file)")
 
 	rootCmd.AddCommand(runCmd)
 }
 
+// addSelectionFlags registers the flags deciding which files a traversal picks on cmd, so every command walking
+// the tree the way run does picks the very same files.
+func addSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&excludePatterns, "exclude", "e", []string{}, "Exclude files/folders matching these patterns (can be used multiple times)")
+	cmd.Flags().StringArrayVarP(&includePatterns, "include", "i", []string{}, "Only output files matching these patterns, \"+pattern\" also overrides default exclusions (can be used multiple times)")
+	cmd.Flags().BoolVar(&gitIgnoreEnabled, "gitignore", true, "Also honor .gitignore, .git/info/exclude and core.excludesFile")
+	cmd.Flags().BoolVar(&strictIgnoreFiles, "strict", false, "Fail if \"treeclip ignore lint\" finds issues in .treeclipignore files")
+	cmd.Flags().StringSliceVar(&profileNames, "profile", []string{}, "Default exclusion profiles to use instead of detecting them ("+strings.Join(exclude.ProfileNames(), ", ")+")")
+	cmd.Flags().BoolVar(&noDefaultExcludes, "no-default-excludes", false, "Disable the common default exclusions and profile detection (--profile still applies)")
+	cmd.Flags().BoolVar(&skipGenerated, "skip-generated", true, "Skip generated code, detected by content markers and linguist-generated/linguist-vendored in .gitattributes")
+	cmd.Flags().StringArrayVar(&generatedMarkers, "generated-marker", traversal.DefaultGeneratedMarkers, "Regular expressions marking generated code when found in a file's first lines")
+	cmd.Flags().IntVar(&generatedLines, "generated-lines", traversal.DefaultGeneratedLines, "Number of leading lines searched for generated code markers")
+	cmd.Flags().StringVar(&gitSelection.ChangedSince, "changed-since", "", "Only output files changed since this git revision, committed or not")
+	cmd.Flags().BoolVar(&gitSelection.Staged, "staged", false, "Only output files with staged changes")
+	cmd.Flags().BoolVar(&gitSelection.Unstaged, "unstaged", false, "Only output files with unstaged changes")
+	cmd.Flags().BoolVar(&gitSelection.Untracked, "untracked", false, "Only output untracked files (not the ignored ones)")
+	cmd.Flags().BoolVar(&filesFromStdin, "from-stdin", false, "Output the files listed on stdin instead of walking the folder, one per line or NUL-separated")
+	cmd.Flags().StringVar(&filesFrom, "files-from", "", "Output the files listed in this file instead of walking the folder, one per line or NUL-separated")
+	cmd.Flags().StringVar(&revision, "rev", "", "Read the files of this git commit or tag instead of the working tree")
+	cmd.Flags().StringVar(&modifiedWithin, "modified-within", "", "Only output files modified within this duration, e.g. 2h, 45m or 3d")
+	cmd.Flags().StringVar(&modifiedSince, "modified-since", "", "Only output files modified since this time, RFC3339 (2024-05-01T14:00:00+02:00) or a date (2024-05-01)")
+	cmd.Flags().StringVar(&newerThan, "newer-than", "", "Only output files modified after this file was")
+	cmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Only descend this many folder levels, 1 being the files of the root (default: no limit)")
+	cmd.Flags().IntVarP(&readJobs, "jobs", "j", runtime.NumCPU(), "Number of files read in parallel, the output order stays the same")
+	cmd.Flags().DurationVar(&readTimeout, "read-timeout", 30*time.Second, "Give up on files that take longer than this to read, 0 waits forever")
+	cmd.Flags().StringVar(&onError, "on-error", string(traversal.OnErrorAbort), "What to do with unreadable files: abort, skip (marker in the output, exit code 3) or warn (skip and print right away)")
+	cmd.Flags().BoolVarP(&followSymlinks, "follow-symlinks", "L", false, "Follow symlinked files and folders instead of listing them as \"link -> target\"")
+}
+
 // runCmd concatenates the contents of all files in a given directory and writes them to a text file.
 var runCmd = &cobra.Command{
-	Use:   "run [path | cwd if empty]",
+	Use:   "run [paths... | archive | cwd if empty]",
 	Short: "Traverse a folder and output all file contents into a .txt file",
 	Long:  generateLongDescription(),
-	Args:  cobra.MaximumNArgs(1),
+	Args:  cobra.ArbitraryArgs,
 	RunE:  registerRunCmd(),
 }
 
@@ -51,18 +124,107 @@ func generateLongDescription() string {
 Examples:
   treeclip run                                     # Current directory, copy to clipboard
   treeclip run /path/to/dir                        # Specific directory
+  treeclip run cmd/ internal/git go.mod            # Several folders and files, each labeled
   treeclip run --exclude "*.log" --exclude "*.tmp" # Exclude patterns
   treeclip run -e "*.md" -e "folder1" -e "app.go"  # Multiple exclusions
+  treeclip run -e "docs/**" -e "!docs/api.md"      # Gitignore-style patterns, "!" re-includes
+  treeclip run -i "internal/**/*.go" -i "*.sql"    # Only output matching files (also read from .treeclipinclude)
+  treeclip run -i "+go.sum"                        # Keep a file despite the default exclusions
+  treeclip run -e 're:_gen\.go$' -e '!api/**'      # Regular expression on the relative path
+  treeclip run -i "ext:go,sql"                     # Only .go and .sql files
   treeclip run --stats                             # Show content statistics
   treeclip run --editor                            # Open output file in the default text editor
-  treeclip run --delete                            # Delete the output file after editor is closed`
+  treeclip run --delete                            # Delete the output file after editor is closed
+  treeclip run --gitignore=false                   # Ignore git's exclude rules, only use .treeclipignore
+  treeclip run --strict                            # Fail on invalid, unused or shadowed .treeclipignore patterns
+  treeclip run --profile node,python               # Use these profiles instead of detecting them
+  treeclip run --no-default-excludes               # No built-in exclusions at all
+  treeclip run --max-file-size 512KB               # Placeholder instead of the content of larger files
+  treeclip run --max-total-size 5MB                # Keep the file contents under 5 MB in total
+  treeclip run --generated-marker "^# AUTOGENERATED" # Custom generated code marker (replaces the defaults)
+  treeclip run --skip-generated=false              # Keep generated and vendored code
+  treeclip run --changed-since main                # Only files changed since main, committed or not
+  treeclip run --staged --untracked                # Only staged and untracked files
+  treeclip run --rev v1.3                          # The tree as it was at tag v1.3, without checking it out
+  treeclip run --modified-within 4h                # Everything edited in the last 4 hours
+  treeclip run --newer-than build/app.bin          # Everything edited since the last build
+  treeclip run --jobs 16                           # Read 16 files at a time (network filesystems, cold caches)
+  treeclip run --sort mtime                        # Most recently modified files first
+  treeclip run -p "README*,go.mod" -p "cmd/**"     # These files first, then the rest
+  treeclip run --follow-symlinks                   # Read symlinked files and folders (cycles are detected)
+  treeclip run --on-error warn                     # Keep going past unreadable files, list them at the end
+  treeclip run --max-depth 2                       # Root files and those one folder down
+  rg -l TODO | treeclip run --from-stdin           # Only the files a search found
+  git ls-files -z | treeclip run --from-stdin      # NUL-separated lists work too
+  treeclip run --files-from review.txt             # Files listed in a file, one per line
+  treeclip run release-1.4.tar.gz                  # The files of an archive, without extracting it
+  treeclip run cmd/run.go:120-240 go.mod           # Only lines 120 to 240 of run.go ("120-" runs to the end)
+  treeclip run cmd/run.go#openInput                # Only the declaration of openInput (Go files, "Type.Method" too)
+  treeclip run --format md                         # Markdown: a "### path" heading and a code block per file
+
+Exclusions are applied in this order, a later "!pattern" can re-include what an earlier source excluded:
+  default exclusions < .gitignore & git excludes < .treeclipignore < --exclude
+Nested .gitignore and .treeclipignore files only apply to their own directory and below.
+
+Generated files are skipped when one of their first lines matches a marker (Go's "// Code generated ...
+DO NOT EDIT." and "@generated" by default) or .gitattributes marks them linguist-generated or linguist-vendored.
+--changed-since, --staged, --unstaged and --untracked select the union of the files git reports in those
+states, which then go through the exclusions above. They require a git repository and the git binary.
+--rev reads the files, .gitignore, .treeclipignore and .gitattributes files of a commit instead of the working tree.
+A .zip, .tar, .tar.gz or .tgz path is read as the folder it contains, its own ignore files included. Tar archives
+are loaded in memory without their files over --max-file-size, and refused above 1 GB of remaining content.
+--from-stdin and --files-from read the listed files (relative to the current folder) instead of walking the folder;
+they still go through the exclusions above, including those of their parent folders, and the size limits.
+Several paths are read from their closest common parent folder: output paths are relative to it, and each file
+is written once, under the label of the first path holding it. The named paths themselves are never excluded.
+A file path can end in :120-240 or #Name to only write those lines or that Go declaration (its doc comment
+included), headed by the lines it covers; --max-file-size then applies to the selected lines.
+--modified-within, --modified-since and --newer-than skip files modified earlier; combined, the latest time wins.
+
+Binary files and files over a size limit are listed with a placeholder such as "[binary, 1.2 MB, skipped]"
+instead of their content. --max-total-size only counts file contents, not headers or placeholders: a file that
+would push the total over it gets a placeholder, and later files that still fit are written. FIFOs, sockets and
+devices are never opened, and files that take longer than --read-timeout to read are given up on.
+
+The default exclusions are the common profile (VCS folders, editor, OS and temp files) plus the ecosystem
+profiles detected from marker files in the root folder:
+  go         go.mod, go.work
+  node       package.json
+  python     pyproject.toml, setup.py, setup.cfg, requirements.txt, Pipfile
+  rust       Cargo.toml
+  java       pom.xml, build.gradle, settings.gradle
+  dotnet     *.sln, *.csproj, *.fsproj, *.vbproj, global.json
+  terraform  *.tf`
 }
 
 // registerRunCmd handles the actual logic for treeclip dir traversal.
 func registerRunCmd() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		// Determine root path
-		rootDir, err := determineRootDir(args)
+		// Pick the tree to read: the directory, its state at a git revision or an archive
+		input, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+
+		// Load exclusions, the file lists and git's selection are read once here
+		profiles, err := activeProfiles(input)
+		if err != nil {
+			return err
+		}
+		opts, err := traversalOptions(input, profiles)
+		if err != nil {
+			return err
+		}
+
+		// Validate ignore files before writing anything
+		if strictIgnoreFiles {
+			if err := lintIgnoreFiles(input, opts); err != nil {
+				return err
+			}
+		}
+
+		formatter, err := output.NewFormatter(outputFormat)
 		if err != nil {
 			return err
 		}
@@ -72,21 +234,32 @@ func registerRunCmd() func(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to create output file: %w", err)
 		}
-		fileUtils.WriteDataLn(outF, "// 💡Paths are displayed in Unix-style format (forward slashes)")
+		notes := []string{"💡Paths are displayed in Unix-style format (forward slashes)"}
+		if input.rev != nil {
+			notes = append(notes, fmt.Sprintf("🌿Revision %s, commit %s", input.rev.Rev, input.rev.Commit))
+		}
+		if input.archive != nil {
+			notes = append(notes, fmt.Sprintf("📦Archive %s", filepath.Base(input.dir)))
+		}
+		if len(input.roots) > 0 {
+			notes = append(notes, fmt.Sprintf("📁Paths are relative to %s", input.dir))
+		}
+		formatter.BeginDocument(outF, notes)
 
-		// Load exclusions
-		ignoreFilePatterns, err := exclude.LoadIgnorePatterns(rootDir)
-		if err != nil {
+		if opts.MaxFileSize, err = parseSizeFlag("--max-file-size", maxFileSize); err != nil {
+			return err
+		}
+		if opts.MaxTotalSize, err = parseSizeFlag("--max-total-size", maxTotalSize); err != nil {
 			return err
 		}
-		allEx := append(excludePatterns, ignoreFilePatterns...)
-		allEx = append(allEx, exclude.DefaultExclusions...)
 
 		// Traverse and write
-		filesProcessed, filesSkipped, err := traversal.TraverseDir(rootDir, allEx, outF)
+		opts.Formatter = formatter
+		stats, err := traversal.TraverseTree(input.Tree, opts, outF)
 		if err != nil {
 			return err
 		}
+		formatter.EndDocument(outF)
 
 		fileUtils.SafeCloseFile(outF)
 
@@ -102,14 +275,607 @@ func registerRunCmd() func(cmd *cobra.Command, args []string) error {
 
 		fmt.Printf("\n------------ (●'◡'●) ------------\n")
 		fmt.Printf("🎉  Process completed! ＼(＾▽＾)／\n")
-		fmt.Printf("📊  Files processed: %d (•̀ᴗ•́)و\n", filesProcessed)
-		fmt.Printf("🚫  Files/folders skipped: %d (；一_一)\n", filesSkipped)
+		if opts.Only != nil {
+			fmt.Printf("🌿  Files selected by git: %d (•‿•)\n", opts.Only.Len())
+		}
+		if opts.Files != nil {
+			fmt.Printf("📜  Files listed: %d (•‿•)\n", len(opts.Files))
+		}
+		fmt.Printf("📊  Files processed: %d (•̀ᴗ•́)و\n", stats.Processed)
+		fmt.Printf("🚫  Files/folders skipped: %d (；一_一)\n", stats.Skipped)
+		if stats.TooDeep > 0 {
+			fmt.Printf("📏  Folders at --max-depth not entered: %d (￣ー￣)\n", stats.TooDeep)
+		}
+		if stats.Older > 0 {
+			fmt.Printf("⏳  Files older than the time filter skipped: %d (－_－) zzZ\n", stats.Older)
+		}
+		if stats.Generated > 0 {
+			fmt.Printf("🤖  Generated files skipped: %d (－‸ლ)\n", stats.Generated)
+		}
+		if stats.Binary > 0 {
+			fmt.Printf("🧱  Binary files skipped: %d (¬_¬)\n", stats.Binary)
+		}
+		if stats.Symlinks > 0 {
+			fmt.Printf("🔗  Symlinks listed: %d (•_•)>⌐■-■\n", stats.Symlinks)
+		}
+		if stats.Special > 0 {
+			fmt.Printf("🚧  Special files (FIFOs, sockets, devices) skipped: %d (・・;)\n", stats.Special)
+		}
+		if stats.TimedOut > 0 {
+			fmt.Printf("⌛  Files that timed out skipped: %d (╥﹏╥)\n", stats.TimedOut)
+		}
+		if stats.TooLarge > 0 {
+			fmt.Printf("🐘  Files over the size limit skipped: %d (⊙_⊙)\n", stats.TooLarge)
+		}
 		fmt.Printf("📄  Output file: %s (ᵔ◡ᵔ)\n", outputFile)
+		if showClipboardStats {
+			fmt.Printf("🧩  Active profiles: %s (⌐■_■)\n", profileList(profiles))
+		}
+		if len(stats.Failures) > 0 {
+			fmt.Printf("💥  Unreadable files/folders skipped: %d (╯°□°）╯︵ ┻━┻\n", len(stats.Failures))
+			for _, failure := range stats.Failures {
+				fmt.Printf("   ❌  %s\n", failure)
+			}
+		}
 		fmt.Println("\n  totoro!  ㄟ( ▔, ▔ )ㄏ")
+
+		if len(stats.Failures) > 0 {
+			cmd.SilenceUsage = true
+			return &exitError{code: exitPartial, err: fmt.Errorf("%d file(s)/folder(s) could not be read (；一_一)", len(stats.Failures))}
+		}
 		return nil
 	}
 }
 
+// inputTree is the tree a command reads: the root directory itself, its state at --rev or the content of an archive.
+type inputTree struct {
+	traversal.Tree
+	dir     string                     // dir is the root directory or archive path given on the command line.
+	roots   []string                   // roots are the slash-separated files and directories to read below dir, all of it when empty.
+	lines   map[string][]snippet.Range // lines are the line ranges selected by "file:120-240" and "file#Name" arguments.
+	rev     *git.TreeFS                // rev is set with --rev.
+	archive archive.FS                 // archive is set when dir is a zip or tar archive.
+}
+
+// dirInput is the inputTree of the directory rootDir on disk.
+func dirInput(rootDir string) inputTree {
+	return inputTree{Tree: traversal.DirTree(rootDir), dir: rootDir}
+}
+
+// openInput opens the tree run and tree read from their arguments: the current directory without any, a single
+// directory or archive, or otherwise any mix of files and directories, read from their closest common parent.
+func openInput(args []string) (inputTree, error) {
+	paths, selections, err := splitLineSelections(args)
+	if err != nil {
+		return inputTree{}, err
+	}
+	if len(args) <= 1 && len(selections) == 0 {
+		rootDir, err := determineRootDir(args)
+		if err != nil {
+			return inputTree{}, err
+		}
+		if info, err := os.Stat(rootDir); err != nil || info.IsDir() || archive.IsArchive(rootDir) {
+			return openTree(rootDir)
+		}
+	}
+
+	rootDir, roots, err := commonRoot(paths)
+	if err != nil {
+		return inputTree{}, err
+	}
+	input, err := openTree(rootDir)
+	if err != nil {
+		return inputTree{}, err
+	}
+	input.roots = roots
+	if input.lines, err = resolveLineSelections(input, selections); err != nil {
+		_ = input.Close()
+		return inputTree{}, err
+	}
+	return input, nil
+}
+
+// splitLineSelections splits the line selection off arguments such as "file.go:120-240" or "file.go#Name",
+// returning the paths and the selections by argument index. An argument naming an existing path is taken as is.
+func splitLineSelections(args []string) (paths []string, selections map[int]snippet.Range, err error) {
+	paths = slices.Clone(args)
+	for i, arg := range args {
+		if _, err := os.Stat(arg); err == nil {
+			continue
+		}
+		var selection snippet.Range
+		if filePath, symbol, found := cutLast(arg, "#"); found && exists(filePath) {
+			paths[i], selection.Symbol = filePath, symbol
+		} else if filePath, spec, found := cutLast(arg, ":"); found && exists(filePath) {
+			if selection, err = snippet.ParseRange(spec); err != nil {
+				return nil, nil, err
+			}
+			paths[i] = filePath
+		} else {
+			continue
+		}
+		if info, err := os.Stat(paths[i]); err != nil || info.IsDir() || archive.IsArchive(paths[i]) {
+			return nil, nil, fmt.Errorf("%s: lines can only be selected in a file (ノಠ益ಠ)ノ", arg)
+		}
+		if selections == nil {
+			selections = map[int]snippet.Range{}
+		}
+		selections[i] = selection
+	}
+	return paths, selections, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// exists reports whether filePath exists.
+func exists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// resolveLineSelections keys the line selections by the root they were given for, resolving "#Name" selections
+// to the lines of that declaration in the file as input holds it.
+func resolveLineSelections(input inputTree, selections map[int]snippet.Range) (map[string][]snippet.Range, error) {
+	if len(selections) == 0 {
+		return nil, nil
+	}
+	lines := map[string][]snippet.Range{}
+	for i, rel := range input.roots {
+		selection, found := selections[i]
+		if !found {
+			continue
+		}
+		if selection.Symbol != "" {
+			if path.Ext(rel) != ".go" {
+				return nil, fmt.Errorf("%s: #%s can only name a declaration of a Go file (ノಠ益ಠ)ノ", rel, selection.Symbol)
+			}
+			src, err := fs.ReadFile(input.FS, rel)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w (ノಠ益ಠ)ノ", input.Name(rel), err)
+			}
+			if selection, err = snippet.GoSymbol(rel, src, selection.Symbol); err != nil {
+				return nil, err
+			}
+		}
+		lines[rel] = append(lines[rel], selection)
+	}
+	return lines, nil
+}
+
+// commonRoot resolves path arguments to their closest common parent directory and their paths relative to it.
+func commonRoot(args []string) (rootDir string, roots []string, err error) {
+	absPaths := make([]string, 0, len(args))
+	for _, arg := range args {
+		absPath, err := filepath.Abs(arg)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid path: %w", err)
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid path: %w (ノಠ益ಠ)ノ", err)
+		}
+		if !info.IsDir() && archive.IsArchive(absPath) {
+			return "", nil, fmt.Errorf("%s is an archive, archives can only be read on their own (ノಠ益ಠ)ノ", arg)
+		}
+
+		dir := absPath
+		if !info.IsDir() {
+			dir = filepath.Dir(absPath)
+		}
+		if rootDir == "" {
+			rootDir = dir
+		}
+		for !isWithin(dir, rootDir) {
+			parent := filepath.Dir(rootDir)
+			if parent == rootDir {
+				return "", nil, fmt.Errorf("%s and %s have no common parent folder (ノಠ益ಠ)ノ", args[0], arg)
+			}
+			rootDir = parent
+		}
+		absPaths = append(absPaths, absPath)
+	}
+
+	for _, absPath := range absPaths {
+		rel, err := filepath.Rel(rootDir, absPath)
+		if err != nil {
+			return "", nil, err
+		}
+		roots = append(roots, filepath.ToSlash(rel))
+	}
+	return rootDir, roots, nil
+}
+
+// isWithin reports whether the absolute path p is dir or inside it.
+func isWithin(p, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// openTree returns the tree to read for rootDir: the directory itself, its state at --rev, or the content of
+// rootDir when it is a zip or tar archive. The result must be closed.
+func openTree(rootDir string) (inputTree, error) {
+	if info, err := os.Stat(rootDir); err == nil && info.Mode().IsRegular() && archive.IsArchive(rootDir) {
+		if revision != "" || !gitSelection.Empty() {
+			return inputTree{}, fmt.Errorf("%s is an archive, --rev and the git file selection flags need a directory (ノಠ益ಠ)ノ", rootDir)
+		}
+		// Tar bodies over --max-file-size would only be written as a placeholder, they aren't loaded
+		limit, err := parseSizeFlag("--max-file-size", maxFileSize)
+		if err != nil {
+			return inputTree{}, err
+		}
+		archiveFS, err := archive.Open(rootDir, limit)
+		if err != nil {
+			return inputTree{}, err
+		}
+		return inputTree{Tree: traversal.FSTree(archiveFS, rootDir), dir: rootDir, archive: archiveFS}, nil
+	}
+
+	if revision == "" {
+		return dirInput(rootDir), nil
+	}
+	if !gitSelection.Empty() {
+		return inputTree{}, fmt.Errorf("--rev can't be combined with --changed-since, --staged, --unstaged or --untracked (ノಠ益ಠ)ノ")
+	}
+	revTree, err := git.OpenTree(rootDir, revision)
+	if err != nil {
+		return inputTree{}, err
+	}
+	return inputTree{Tree: traversal.Tree{FS: revTree, Name: revTree.Name}, dir: rootDir, rev: revTree}, nil
+}
+
+// Close closes the revision or archive being read.
+func (t inputTree) Close() error {
+	switch {
+	case t.rev != nil:
+		return t.rev.Close()
+	case t.archive != nil:
+		return t.archive.Close()
+	}
+	return nil
+}
+
+// traversalOptions merges every exclusion source for input. Later sources win, from the most general to the most specific:
+//
+//  1. the patterns of the active profiles
+//  2. git's ignore rules outside the root (global excludes file, .git/info/exclude, parent .gitignore files)
+//  3. .gitignore files inside the root, each scoped to its directory (2 and 3 are skipped with --gitignore=false)
+//  4. .treeclipignore files inside the root, each scoped to its directory
+//  5. --exclude flags
+//  6. the output file, when it lies inside the directory being read
+//
+// An archive has no outside, so 2 and the .gitattributes files outside the root are skipped for it.
+func traversalOptions(input inputTree, profiles []exclude.Profile) (traversal.Options, error) {
+	defaults, err := exclude.ProfilePatterns(profiles)
+	if err != nil {
+		return traversal.Options{}, err
+	}
+	overrides, err := exclude.ParsePatterns(splitPatternFlag(excludePatterns), exclude.FlagSource("--exclude"))
+	if err != nil {
+		return traversal.Options{}, err
+	}
+	if outputPattern, found := outputFilePattern(input); found {
+		overrides = append(overrides, outputPattern)
+	}
+	opts := traversal.Options{
+		Defaults:    defaults,
+		IgnoreFiles: []string{exclude.IgnoreFileName},
+		Overrides:   overrides,
+	}
+
+	// Allowlist from .treeclipinclude and --include, "+pattern" forces a path in despite the default exclusions
+	fileIncludes, fileForced, err := exclude.LoadIncludePatternsFS(input.FS, input.Name(exclude.IncludeFileName))
+	if err != nil {
+		return traversal.Options{}, err
+	}
+	flagIncludes, flagForced, err := exclude.ParseIncludePatterns(splitPatternFlag(includePatterns), exclude.FlagSource("--include"))
+	if err != nil {
+		return traversal.Options{}, err
+	}
+	opts.Includes = append(fileIncludes, flagIncludes...)
+	opts.ForcedIncludes = append(fileForced, flagForced...)
+
+	if gitIgnoreEnabled {
+		if input.archive == nil {
+			if opts.Patterns, err = exclude.LoadGitIgnorePatterns(input.dir); err != nil {
+				return traversal.Options{}, err
+			}
+		}
+		opts.IgnoreFiles = []string{exclude.GitIgnoreFileName, exclude.IgnoreFileName}
+	}
+
+	if skipGenerated {
+		for _, marker := range generatedMarkers {
+			re, err := regexp.Compile(marker)
+			if err != nil {
+				return traversal.Options{}, fmt.Errorf("invalid --generated-marker %q: %w (ノಠ益ಠ)ノ", marker, err)
+			}
+			opts.GeneratedMarkers = append(opts.GeneratedMarkers, re)
+		}
+		opts.GeneratedLines = generatedLines
+
+		if input.archive == nil {
+			if opts.Attributes, err = exclude.LoadGitAttributePatterns(input.dir, exclude.GeneratedAttributes); err != nil {
+				return traversal.Options{}, err
+			}
+		}
+		opts.AttributeFiles = []string{exclude.GitAttributesFileName}
+	}
+
+	if maxDepth < 0 {
+		return traversal.Options{}, fmt.Errorf("invalid --max-depth %d, expected 0 (no limit) or more (ノಠ益ಠ)ノ", maxDepth)
+	}
+	opts.MaxDepth = maxDepth
+	opts.Roots = input.roots
+	opts.Lines = input.lines
+	if filesFromStdin || filesFrom != "" {
+		if opts.Files, err = listedFiles(input); err != nil {
+			return traversal.Options{}, err
+		}
+	}
+	opts.Jobs = readJobs
+	opts.FollowSymlinks = followSymlinks
+	opts.ReadTimeout = readTimeout
+	if opts.OnError, err = traversal.ParseErrorMode(onError); err != nil {
+		return traversal.Options{}, err
+	}
+	if opts.Sort, err = traversal.ParseSortOrder(sortOrder); err != nil {
+		return traversal.Options{}, err
+	}
+	if opts.Priority, err = exclude.ParsePatterns(splitPatternFlag(priorityPatterns), exclude.FlagSource("--priority")); err != nil {
+		return traversal.Options{}, err
+	}
+	if opts.ModifiedSince, err = modifiedSinceFlags(); err != nil {
+		return traversal.Options{}, err
+	}
+
+	if !gitSelection.Empty() {
+		files, err := git.ChangedFiles(input.dir, gitSelection)
+		if err != nil {
+			return traversal.Options{}, err
+		}
+		opts.Only = traversal.NewFileSet(files)
+	}
+	return opts, nil
+}
+
+// listedFiles reads the --from-stdin and --files-from lists as slash-separated paths relative to the root of input.
+// Listed paths are relative to the current directory, or to the archive's root when reading an archive.
+func listedFiles(input inputTree) ([]string, error) {
+	if len(input.roots) > 0 {
+		return nil, fmt.Errorf("--from-stdin and --files-from take a single folder, not several paths (ノಠ益ಠ)ノ")
+	}
+
+	var lists [][]byte
+	if filesFromStdin {
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return nil, fmt.Errorf("--from-stdin needs a file list piped in, e.g. git ls-files -z | treeclip run --from-stdin (ノಠ益ಠ)ノ")
+		}
+		list, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the file list from stdin: %w", err)
+		}
+		lists = append(lists, list)
+	}
+	if filesFrom != "" {
+		list, err := os.ReadFile(filesFrom)
+		if err != nil {
+			return nil, fmt.Errorf("--files-from: %w (ノಠ益ಠ)ノ", err)
+		}
+		lists = append(lists, list)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cwd: %w", err)
+	}
+	files := []string{}
+	for _, list := range lists {
+		for _, listed := range splitFileList(list) {
+			if input.archive != nil {
+				files = append(files, path.Clean(strings.TrimPrefix(filepath.ToSlash(listed), "/")))
+				continue
+			}
+			absPath := listed
+			if !filepath.IsAbs(absPath) {
+				absPath = filepath.Join(cwd, listed)
+			}
+			if !isWithin(absPath, input.dir) {
+				return nil, fmt.Errorf("listed path %s is outside of %s (ノಠ益ಠ)ノ", listed, input.dir)
+			}
+			rel, err := filepath.Rel(input.dir, absPath)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+	}
+	return files, nil
+}
+
+// splitFileList splits a file list on NUL bytes when it has any, like git ls-files -z or fd -0 print it,
+// and on newlines otherwise. Empty entries are dropped.
+func splitFileList(list []byte) []string {
+	separator := "\n"
+	if bytes.IndexByte(list, 0) >= 0 {
+		separator = "\x00"
+	}
+	var files []string
+	for _, file := range strings.Split(string(list), separator) {
+		if file = strings.TrimSuffix(file, "\r"); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// outputFilePattern returns the pattern excluding the output file from input, found when input is a directory on
+// disk holding it. The file is being written while input is read, so it never belongs to the output.
+func outputFilePattern(input inputTree) (exclude.Pattern, bool) {
+	if input.rev != nil || input.archive != nil {
+		return exclude.Pattern{}, false
+	}
+	absOutput, err := filepath.Abs(outputFile)
+	if err != nil {
+		return exclude.Pattern{}, false
+	}
+	absDir, err := filepath.Abs(input.dir)
+	if err != nil || !isWithin(absOutput, absDir) {
+		return exclude.Pattern{}, false
+	}
+	rel, err := filepath.Rel(absDir, absOutput)
+	if err != nil {
+		return exclude.Pattern{}, false
+	}
+	return exclude.PathPattern(filepath.ToSlash(rel), exclude.OutputSource(outputFile)), true
+}
+
+// activeProfiles resolves the default exclusion profiles for input: the common profile unless --no-default-excludes
+// is set, then the --profile ones if given, otherwise the ones detected from the marker files in the root of input.
+func activeProfiles(input inputTree) ([]exclude.Profile, error) {
+	var profiles []exclude.Profile
+	if !noDefaultExcludes {
+		profiles = append(profiles, exclude.CommonProfile)
+	}
+
+	if len(profileNames) > 0 {
+		for _, name := range profileNames {
+			profile, err := exclude.LookupProfile(name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.ContainsFunc(profiles, func(p exclude.Profile) bool { return p.Name == profile.Name }) {
+				profiles = append(profiles, profile)
+			}
+		}
+		return profiles, nil
+	}
+	if noDefaultExcludes {
+		return profiles, nil
+	}
+
+	// Several roots each bring the profiles of their own marker files
+	dirs := []string{"."}
+	for _, root := range input.roots {
+		if info, err := fs.Stat(input.FS, root); err == nil && info.IsDir() && root != "." {
+			dirs = append(dirs, root)
+		}
+	}
+	for _, dir := range dirs {
+		dirFS, err := fs.Sub(input.FS, dir)
+		if err != nil {
+			return nil, err
+		}
+		detected, err := exclude.DetectProfilesFS(dirFS)
+		if err != nil {
+			return nil, err
+		}
+		for _, profile := range detected {
+			if !slices.ContainsFunc(profiles, func(p exclude.Profile) bool { return p.Name == profile.Name }) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// profileList formats the profile names for the summary.
+func profileList(profiles []exclude.Profile) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// modifiedSinceFlags merges --modified-within, --modified-since and --newer-than into the latest of their times.
+// The zero time means no filter.
+func modifiedSinceFlags() (time.Time, error) {
+	var since time.Time
+	if modifiedWithin != "" {
+		age, err := parseAge(modifiedWithin)
+		if err != nil {
+			return time.Time{}, err
+		}
+		since = time.Now().Add(-age)
+	}
+	if modifiedSince != "" {
+		t, err := time.Parse(time.RFC3339, modifiedSince)
+		if err != nil {
+			if t, err = time.ParseInLocation(time.DateOnly, modifiedSince, time.Local); err != nil {
+				return time.Time{}, fmt.Errorf("invalid --modified-since %q, expected RFC3339 or YYYY-MM-DD (ノಠ益ಠ)ノ", modifiedSince)
+			}
+		}
+		since = latest(since, t)
+	}
+	if newerThan != "" {
+		info, err := os.Stat(newerThan)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("--newer-than: %w (ノಠ益ಠ)ノ", err)
+		}
+		// strictly newer, like find -newer
+		since = latest(since, info.ModTime().Add(time.Nanosecond))
+	}
+	return since, nil
+}
+
+// parseAge parses a --modified-within duration: anything time.ParseDuration accepts, plus days such as "3d".
+func parseAge(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.ParseFloat(days, 64); err == nil && n >= 0 {
+			return time.Duration(n * float64(24*time.Hour)), nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid --modified-within %q, expected e.g. 90m, 2h or 3d (ノಠ益ಠ)ノ", value)
+	}
+	return age, nil
+}
+
+// latest returns the later of two times.
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// parseSizeFlag parses the value of a size limit flag, an empty value means no limit.
+func parseSizeFlag(name, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := fileUtils.ParseBytes(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return size, nil
+}
+
+// splitPatternFlag splits comma-separated pattern flag values, except for "re:" and "ext:" patterns whose commas
+// belong to the pattern itself.
+func splitPatternFlag(values []string) []string {
+	var patterns []string
+	for _, value := range values {
+		bare := strings.TrimLeft(value, "!+")
+		if strings.HasPrefix(bare, "re:") || strings.HasPrefix(bare, "ext:") {
+			patterns = append(patterns, value)
+			continue
+		}
+		patterns = append(patterns, strings.Split(value, ",")...)
+	}
+	return patterns
+}
+
 // determineRootDir determines the root directory to traverse to.
 func determineRootDir(args []string) (string, error) {
 	rootDir := "."
@@ -0,0 +1,97 @@
+// Package cmd. treeCmd prints the directory structure run would output, without the file contents.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/seyedali-dev/treeclip/internal/clipboard"
+	"github.com/seyedali-dev/treeclip/internal/output"
+	"github.com/seyedali-dev/treeclip/internal/traversal"
+	"github.com/spf13/cobra"
+)
+
+// pruneEmptyDirs drops folders without any listed file from the tree.
+var pruneEmptyDirs bool
+
+func init() {
+	addSelectionFlags(treeCmd)
+	treeCmd.Flags().BoolVarP(&clipboardEnabled, "clipboard", "c", true, "Copy the tree to clipboard")
+	treeCmd.Flags().BoolVar(&showClipboardStats, "stats", false, "Show clipboard content statistics")
+	treeCmd.Flags().BoolVar(&pruneEmptyDirs, "prune", false, "Leave out folders that end up without any file, e.g. with --include")
+	treeCmd.Flags().StringVar(&outputFormat, "format", "text", "Output format: text or md (Markdown, the tree in a code block)")
+
+	rootCmd.AddCommand(treeCmd)
+}
+
+// treeCmd prints the files and folders run would output as a tree and copies it to the clipboard.
+var treeCmd = &cobra.Command{
+	Use:   "tree [paths... | archive | cwd if empty]",
+	Short: "Print the folder structure run would output, with file sizes",
+	Long: `Print the files and folders run would output as a tree, with file sizes, and copy it to the clipboard.
+Handy to show the layout of a project first and send the contents later.
+
+Takes the same exclusion, git selection, time filter and symlink flags as run and picks exactly the same files.
+Binary and large files are listed with their size, generated files are left out like run does.
+
+Examples:
+  treeclip tree                                    # Current directory, copy to clipboard
+  treeclip tree /path/to/dir --max-depth 2         # Root files and those one folder down
+  treeclip tree -i "ext:go" --prune                # Only .go files and the folders holding them
+  treeclip tree --rev v1.3 --clipboard=false       # The layout at tag v1.3, printed only
+  treeclip tree --format md                        # The tree in a Markdown code block`,
+	Args: cobra.ArbitraryArgs,
+	RunE: registerTreeCmd(),
+}
+
+// registerTreeCmd handles the actual logic for printing the tree.
+func registerTreeCmd() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		formatter, err := output.NewFormatter(outputFormat)
+		if err != nil {
+			return err
+		}
+		input, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+
+		// The options read the file lists and ask git once, for the lint and the listing alike
+		profiles, err := activeProfiles(input)
+		if err != nil {
+			return err
+		}
+		opts, err := traversalOptions(input, profiles)
+		if err != nil {
+			return err
+		}
+		if strictIgnoreFiles {
+			if err := lintIgnoreFiles(input, opts); err != nil {
+				return err
+			}
+		}
+
+		root, stats, err := traversal.ListTree(input.Tree, opts, pruneEmptyDirs)
+		if err != nil {
+			return err
+		}
+		var listing bytes.Buffer
+		formatter.BeginDocument(&listing, nil)
+		formatter.Tree(&listing, root)
+		formatter.EndDocument(&listing)
+		fmt.Print(listing.String())
+
+		clipboard.HandleClipboardContent(clipboardEnabled, showClipboardStats, listing.String())
+
+		if len(stats.Failures) > 0 {
+			fmt.Printf("\n💥  Unreadable files/folders skipped: %d (╯°□°）╯︵ ┻━┻\n", len(stats.Failures))
+			for _, failure := range stats.Failures {
+				fmt.Printf("   ❌  %s\n", failure)
+			}
+			cmd.SilenceUsage = true
+			return &exitError{code: exitPartial, err: fmt.Errorf("%d file(s)/folder(s) could not be read (；一_一)", len(stats.Failures))}
+		}
+		return nil
+	}
+}
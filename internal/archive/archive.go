@@ -0,0 +1,104 @@
+// Package archive opens zip and tar archives as read-only file trees, so they can be traversed without extracting them.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/pkg/utils"
+)
+
+// Extensions are the file name suffixes of the supported archive formats.
+var Extensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// FS is an opened archive. Paths are slash-separated and relative to the archive's root. Symlinks have
+// fs.ModeSymlink, their target is read with ReadLink and is never resolved.
+type FS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+	Close() error
+}
+
+// IsArchive reports whether filePath names a supported archive by its extension.
+func IsArchive(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	for _, ext := range Extensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens the archive at filePath, chosen by its extension. Zip files are read on demand, tar files
+// (gzip-compressed or not) are read into memory once since they can't be seeked by entry: files larger than
+// maxFileSize (0 for no limit) are left out of memory, and archives with more than MaxTarContent bytes of
+// remaining content are refused.
+func Open(filePath string, maxFileSize int64) (FS, error) {
+	lower := strings.ToLower(filePath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		reader, err := zip.OpenReader(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip archive %s: %w (ノಠ益ಠ)ノ", filePath, err)
+		}
+		return &zipFS{ReadCloser: reader}, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return openTar(filePath, false, maxFileSize)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return openTar(filePath, true, maxFileSize)
+	}
+	return nil, fmt.Errorf("%s is not a supported archive, expected one of %s (ノಠ益ಠ)ノ", filePath, strings.Join(Extensions, ", "))
+}
+
+// zipFS is a zip archive, whose reader already implements fs.FS, plus ReadLink.
+type zipFS struct {
+	*zip.ReadCloser
+}
+
+// ReadLink returns the target of the symlink name, which zip stores as the file's content.
+func (z *zipFS) ReadLink(name string) (string, error) {
+	info, err := fs.Stat(z, name)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := fs.ReadFile(z, name)
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
+
+// openTar reads the tar archive at filePath into memory.
+func openTar(filePath string, gzipped bool, maxFileSize int64) (FS, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar archive %s: %w (ノಠ益ಠ)ノ", filePath, err)
+	}
+	defer utils.SafeCloseFile(file)
+
+	var reader io.Reader = file
+	if gzipped {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open tar archive %s: %w (ノಠ益ಠ)ノ", filePath, err)
+		}
+		defer func() { _ = gzipReader.Close() }()
+		reader = gzipReader
+	}
+
+	tarFS, err := readTar(tar.NewReader(reader), maxFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tar archive %s: %w (ノಠ益ಠ)ノ", filePath, err)
+	}
+	return tarFS, nil
+}
@@ -0,0 +1,94 @@
+// Package archive. tar holds the entries of a tar archive in memory and serves them as an fs.FS.
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+	"github.com/seyedali-dev/treeclip/internal/memfs"
+)
+
+// MaxTarContent caps the content a tar archive may keep in memory. Files over the --max-file-size limit are
+// never loaded and don't count against it.
+const MaxTarContent = 1 << 30
+
+// ruleFiles are the ignore, attribute and include files the traversal reads its rules from. They are always
+// loaded, whatever their size.
+var ruleFiles = []string{
+	exclude.GitIgnoreFileName, exclude.IgnoreFileName, exclude.GitAttributesFileName, exclude.IncludeFileName,
+}
+
+// tarFS is the content of a tar archive. Parent directories missing from the archive are added, and a later
+// entry for the same path replaces an earlier one, as extracting the archive would.
+type tarFS struct {
+	*memfs.FS
+}
+
+// readTar reads every entry of the archive. The body of a file larger than maxFileSize is skipped, only its size
+// is kept, unless it is one of the ruleFiles; 0 means no limit.
+func readTar(reader *tar.Reader, maxFileSize int64) (*tarFS, error) {
+	t := &tarFS{FS: memfs.New(time.Time{}, nil)}
+	var kept int64
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if name == "." || !fs.ValidPath(name) {
+			continue
+		}
+		entry := memfs.NewEntry(path.Base(name), header.FileInfo().Mode(), 0, header.ModTime)
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeGNUSparse:
+			if maxFileSize > 0 && header.Size > maxFileSize && !slices.Contains(ruleFiles, path.Base(name)) {
+				// The reader skips the body on the next entry
+				entry = memfs.NewEntry(path.Base(name), header.FileInfo().Mode(), header.Size, header.ModTime)
+				entry.Err = errors.New("larger than --max-file-size, not loaded from the archive")
+				break
+			}
+			if kept += header.Size; kept > MaxTarContent {
+				return nil, fmt.Errorf("more than %d GB of content, set --max-file-size to leave large files out (ノಠ益ಠ)ノ", MaxTarContent>>30)
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+			entry = memfs.NewEntry(path.Base(name), header.FileInfo().Mode(), int64(len(data)), header.ModTime)
+			entry.Data = data
+		case tar.TypeLink:
+			// A hard link shares the content of an earlier entry
+			target, found := t.Lookup(path.Clean(strings.TrimPrefix(header.Linkname, "/")))
+			if !found || target.IsDir() {
+				continue
+			}
+			entry = memfs.NewEntry(path.Base(name), target.Mode(), target.Size(), header.ModTime)
+			entry.Data, entry.Err = target.Data, target.Err
+		case tar.TypeSymlink:
+			entry.Data = []byte(header.Linkname)
+		case tar.TypeDir, tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		default: // extended headers are consumed by the reader, anything else is unknown
+			continue
+		}
+		t.Add(name, entry)
+	}
+	t.Index()
+	return t, nil
+}
+
+// Close releases nothing, the archive was closed once read.
+func (t *tarFS) Close() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+	"github.com/seyedali-dev/treeclip/internal/traversal"
+)
+
+// writeTar returns a tar archive holding files, in the given order.
+func writeTar(t *testing.T, files [][2]string) *tar.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, file := range files {
+		header := &tar.Header{Name: file[0], Mode: 0o644, Size: int64(len(file[1])), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(file[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tar.NewReader(&buf)
+}
+
+// TestReadTarKeepsRuleFiles checks that ignore, attribute and include files are loaded over the size limit, while
+// other large files are left out of memory.
+func TestReadTarKeepsRuleFiles(t *testing.T) {
+	reader := writeTar(t, [][2]string{
+		{".gitignore", "*.log\n"},
+		{".treeclipignore", "tmp/\n"},
+		{".gitattributes", "gen.go linguist-generated\n"},
+		{".treeclipinclude", "*.txt\n"},
+		{"src/.gitignore", "local.txt\n"},
+		{"src/app.txt", "hello world\n"},
+		{"src/debug.log", "noise\n"},
+	})
+	tarFS, err := readTar(reader, 2)
+	if err != nil {
+		t.Fatalf("readTar: %v", err)
+	}
+
+	for _, name := range []string{".gitignore", ".treeclipignore", ".gitattributes", ".treeclipinclude", "src/.gitignore"} {
+		if _, err := fs.ReadFile(tarFS, name); err != nil {
+			t.Errorf("ReadFile(%s): %v", name, err)
+		}
+	}
+	if _, err := fs.ReadFile(tarFS, "src/app.txt"); err == nil {
+		t.Errorf("ReadFile(src/app.txt) succeeded, want it left out of memory")
+	}
+	if info, err := fs.Stat(tarFS, "src/app.txt"); err != nil || info.Size() != int64(len("hello world\n")) {
+		t.Errorf("Stat(src/app.txt) = %v, %v, want the size from the archive", info, err)
+	}
+
+	var out bytes.Buffer
+	opts := traversal.Options{
+		IgnoreFiles: []string{exclude.GitIgnoreFileName, exclude.IgnoreFileName},
+		MaxFileSize: 2,
+	}
+	if _, err := traversal.TraverseTree(traversal.FSTree(tarFS, "ar.tar"), opts, &out); err != nil {
+		t.Fatalf("TraverseTree: %v", err)
+	}
+	if strings.Contains(out.String(), "debug.log") {
+		t.Errorf("output holds src/debug.log, excluded by the root .gitignore:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "==> src/app.txt\n[over --max-file-size") {
+		t.Errorf("output lacks the src/app.txt placeholder:\n%s", out.String())
+	}
+}
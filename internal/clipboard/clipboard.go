@@ -19,32 +19,49 @@ func HandleClipboardCommandFlag(clipboardFlag, clipboardStatsFlag bool, outputFi
 			return fmt.Errorf("failed to read output file for clipboard: %w", err)
 		}
 
-		// Copy to clipboard
-		err = atottoClip.WriteAll(string(clipboardContent))
-		if err != nil {
+		if err := copyContent(string(clipboardContent), clipboardStatsFlag); err != nil {
 			fmt.Printf("⚠️  Warning: failed to copy to clipboard: %v\n", err)
 			fmt.Printf("💡  Content is still available in: %s\n", outputFilePath)
-		} else {
-			fmt.Printf("✅  Content copied to clipboard successfully! ヽ(•‿•)ノ\n")
-
-			// Show clipboard statistics if requested
-			if clipboardStatsFlag {
-				contentStr := string(clipboardContent)
-				lines := strings.Split(contentStr, "\n")
-				chars := len(contentStr)
-				words := len(strings.Fields(contentStr))
-
-				fmt.Printf("📊  Clipboard content stats:\n")
-				fmt.Printf("   📝  Characters: %s\n", utils.FormatNumber(chars))
-				fmt.Printf("   📄  Lines: %s\n", utils.FormatNumber(len(lines)))
-				fmt.Printf("   💬  Words: %s\n", utils.FormatNumber(words))
-
-				// Show size in human-readable format
-				fmt.Printf("   💾  Size: %s\n", utils.FormatBytes(int64(chars)))
-			}
 		}
 	} else {
 		fmt.Printf("\n📋  Clipboard copy skipped (disabled) (︶︹︶)\n")
 	}
 	return nil
 }
+
+// HandleClipboardContent is HandleClipboardCommandFlag for output that only exists in memory, e.g. a printed listing.
+func HandleClipboardContent(clipboardFlag, clipboardStatsFlag bool, content string) {
+	if clipboardFlag {
+		fmt.Printf("\n📋  Copying content to clipboard... (ﾉ◕ヮ◕)ﾉ*:･ﾟ✧\n")
+		if err := copyContent(content, clipboardStatsFlag); err != nil {
+			fmt.Printf("⚠️  Warning: failed to copy to clipboard: %v\n", err)
+		}
+	} else {
+		fmt.Printf("\n📋  Clipboard copy skipped (disabled) (︶︹︶)\n")
+	}
+}
+
+// copyContent copies content to the clipboard and reports success, with statistics if requested.
+func copyContent(contentStr string, clipboardStatsFlag bool) error {
+	// Copy to clipboard
+	if err := atottoClip.WriteAll(contentStr); err != nil {
+		return err
+	}
+	fmt.Printf("✅  Content copied to clipboard successfully! ヽ(•‿•)ノ\n")
+
+	// Show clipboard statistics if requested
+	if clipboardStatsFlag {
+		lines := strings.Split(contentStr, "\n")
+		chars := len(contentStr)
+		words := len(strings.Fields(contentStr))
+
+		fmt.Printf("📊  Clipboard content stats:\n")
+		fmt.Printf("   📝  Characters: %s\n", utils.FormatNumber(chars))
+		fmt.Printf("   📄  Lines: %s\n", utils.FormatNumber(len(lines)))
+		fmt.Printf("   💬  Words: %s\n", utils.FormatNumber(words))
+
+		// Show size in human-readable format
+		fmt.Printf("   💾  Size: %s\n", utils.FormatBytes(int64(chars)))
+	}
+	return nil
+}
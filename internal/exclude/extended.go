@@ -0,0 +1,63 @@
+// Package exclude. extended parses and matches the "re:" and "ext:" pattern forms that globs can't express.
+package exclude
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	regexPrefix = "re:"  // regexPrefix starts a regular expression pattern, e.g. "re:_gen\.go$".
+	extPrefix   = "ext:" // extPrefix starts an extension class pattern, e.g. "ext:go,sql".
+)
+
+// isExtended reports whether line, without its "!" prefix, uses one of the extended forms.
+func isExtended(line string) bool {
+	return strings.HasPrefix(line, regexPrefix) || strings.HasPrefix(line, extPrefix)
+}
+
+// parseExtended fills p from an extended pattern:
+//
+// - "re:<regexp>" is a Go regular expression matched against the slash-separated path relative to the
+// pattern's directory; it matches files and directories and is unanchored unless it uses "^" or "$"
+//
+// - "ext:<ext>[,<ext>...]" matches files ending with any of the comma-separated extensions, e.g. "ext:go,.sql,tar.gz"
+func (p *Pattern) parseExtended(line string) error {
+	if expr, found := strings.CutPrefix(line, regexPrefix); found {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression in pattern %q: %w (ノಠ益ಠ)ノ", p.Raw, err)
+		}
+		p.regex = regex
+		return nil
+	}
+
+	list, _ := strings.CutPrefix(line, extPrefix)
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext == "" || strings.ContainsAny(ext, "/*?[") {
+			return fmt.Errorf("invalid extension list in pattern %q: want e.g. \"ext:go,sql\" (ノಠ益ಠ)ノ", p.Raw)
+		}
+		p.exts = append(p.exts, "."+ext)
+	}
+	return nil
+}
+
+// matchExtended matches an extended pattern against the path segments, already relative to the pattern's directory.
+func (p Pattern) matchExtended(segments []string, isDir bool) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(strings.Join(segments, "/"))
+	}
+
+	if isDir {
+		return false
+	}
+	name := segments[len(segments)-1]
+	for _, ext := range p.exts {
+		if len(name) > len(ext) && strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+// Package exclude. gitattributes reads the linguist attributes that mark files as generated or vendored.
+package exclude
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/internal/git"
+)
+
+// GitAttributesFileName is the name of git's per-directory attributes file.
+const GitAttributesFileName = ".gitattributes"
+
+// GeneratedAttributes are the .gitattributes attributes that mark a file as generated or vendored code.
+var GeneratedAttributes = []string{"linguist-generated", "linguist-vendored"}
+
+// LoadAttributePatterns reads a .gitattributes file and returns the path patterns of the lines touching one of attrs.
+// A line that sets an attribute ("attr" or "attr=true") yields a pattern, a line that unsets it ("-attr", "!attr" or
+// "attr=false") yields a negated one, so the result can be evaluated with last-match-wins like an ignore file.
+// A missing file yields no patterns.
+func LoadAttributePatterns(filePath string, attrs []string) ([]Pattern, error) {
+	lines, err := readPatternLines(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseAttributeLines(lines, FileSource(filePath), attrs)
+}
+
+// LoadAttributePatternsFS is LoadAttributePatterns for the slash-separated name inside fsys.
+func LoadAttributePatternsFS(fsys fs.FS, name string, attrs []string) ([]Pattern, error) {
+	lines, err := readPatternLinesFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return parseAttributeLines(lines, FileSource(name), attrs)
+}
+
+// parseAttributeLines turns the lines of an attributes file into patterns, see LoadAttributePatterns.
+func parseAttributeLines(lines []string, source Source, attrs []string) ([]Pattern, error) {
+	var patterns []Pattern
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		// git rejects negative patterns in attribute files
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		set, found := attributeState(fields[1:], attrs)
+		if !found {
+			continue
+		}
+		raw := fields[0]
+		if !set {
+			raw = "!" + raw
+		}
+		pattern, ok, err := parseLine(raw, source.at(i+1))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// LoadGitAttributePatterns loads the attribute patterns for attrs that apply to rootPath but live outside of it:
+// $GIT_DIR/info/attributes, then the .gitattributes files from the repository top level down to the parent of rootPath.
+// Outside a git repository it returns nothing. The .gitattributes files inside rootPath are read by the traversal
+// and rank above all of these.
+func LoadGitAttributePatterns(rootPath string, attrs []string) ([]Pattern, error) {
+	rootPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := git.FindRepo(rootPath)
+	if !ok {
+		return nil, nil
+	}
+
+	patterns, err := LoadAttributePatterns(filepath.Join(repo.CommonDir(), "info", "attributes"), attrs)
+	if err != nil {
+		return nil, err
+	}
+	patterns = withOuterPath(patterns, repo.TopLevel, rootPath)
+
+	for _, dir := range dirsBetween(repo.TopLevel, rootPath) {
+		if dir == rootPath {
+			continue
+		}
+		filePatterns, err := LoadAttributePatterns(filepath.Join(dir, GitAttributesFileName), attrs)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, withOuterPath(filePatterns, dir, rootPath)...)
+	}
+	return patterns, nil
+}
+
+// attributeState reports whether the attribute list of a line sets or unsets one of attrs. When a line touches
+// several of them, setting any wins.
+func attributeState(fields, attrs []string) (set, found bool) {
+	for _, field := range fields {
+		name, value, hasValue := strings.Cut(field, "=")
+		unset := false
+		if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "!") {
+			name, unset = name[1:], true
+		}
+		if !slices.Contains(attrs, name) {
+			continue
+		}
+		found = true
+		if !unset && (!hasValue || value != "false") {
+			return true, true
+		}
+	}
+	return false, found
+}
@@ -0,0 +1,88 @@
+// Package exclude. gitignore loads the ignore rules git itself would apply to a directory.
+package exclude
+
+import (
+	"path/filepath"
+
+	"github.com/seyedali-dev/treeclip/internal/git"
+)
+
+// GitIgnoreFileName is the name of git's per-directory ignore file.
+const GitIgnoreFileName = ".gitignore"
+
+// LoadGitIgnorePatterns loads the git ignore rules that apply to rootPath but live outside of it.
+// Outside a git repository it returns nothing. Patterns are returned from the lowest to the highest precedence,
+// so they can be evaluated with last-match-wins:
+//
+//  1. core.excludesFile (the global excludes file)
+//  2. $GIT_DIR/info/exclude
+//  3. .gitignore files from the repository top level down to the parent of rootPath
+//
+// The .gitignore files inside rootPath are read by the traversal as it walks, see traversal.Options.
+func LoadGitIgnorePatterns(rootPath string) ([]Pattern, error) {
+	rootPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := git.FindRepo(rootPath)
+	if !ok {
+		return nil, nil
+	}
+
+	repoFiles := []string{
+		git.ExcludesFile(repo.TopLevel),
+		filepath.Join(repo.CommonDir(), "info", "exclude"),
+	}
+	var patterns []Pattern
+	for _, filePath := range repoFiles {
+		if filePath == "" {
+			continue
+		}
+		filePatterns, err := LoadPatternFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, withOuterPath(filePatterns, repo.TopLevel, rootPath)...)
+	}
+
+	// .gitignore files above rootPath apply to the whole traversal
+	for _, dir := range dirsBetween(repo.TopLevel, rootPath) {
+		if dir == rootPath {
+			continue
+		}
+		filePatterns, err := LoadPatternFile(filepath.Join(dir, GitIgnoreFileName))
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, withOuterPath(filePatterns, dir, rootPath)...)
+	}
+	return patterns, nil
+}
+
+// withOuterPath scopes patterns declared in fileDir, an ancestor of rootPath, so they can be matched against paths relative to rootPath.
+func withOuterPath(patterns []Pattern, fileDir, rootPath string) []Pattern {
+	outer, err := filepath.Rel(fileDir, rootPath)
+	if err != nil || outer == "." {
+		return patterns
+	}
+	for i := range patterns {
+		patterns[i].outerPath = filepath.ToSlash(outer)
+	}
+	return patterns
+}
+
+// dirsBetween lists the directories from top down to and including dir. dir must be inside top.
+func dirsBetween(top, dir string) []string {
+	var dirs []string
+	for {
+		dirs = append([]string{dir}, dirs...)
+		if dir == top {
+			return dirs
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
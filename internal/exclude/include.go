@@ -0,0 +1,41 @@
+// Package exclude. include implements the allowlist side of the exclusion rules.
+package exclude
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// IncludeFileName is the name of the optional allowlist file read from the traversal root.
+const IncludeFileName = ".treeclipinclude"
+
+// LoadIncludePatternsFS reads .treeclipinclude from the root of fsys, see ParseIncludePatterns. sourceName is the
+// file's name in pattern sources.
+func LoadIncludePatternsFS(fsys fs.FS, sourceName string) (includes, forced []Pattern, err error) {
+	lines, err := readPatternLinesFS(fsys, IncludeFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ParseIncludePatterns(lines, FileSource(sourceName))
+}
+
+// ParseIncludePatterns parses allowlist patterns declared by source, which use the same syntax as exclusions.
+// A "+" prefix marks a forced include: it does not turn on the allowlist by itself, but it overrides
+// the default exclusions for the paths it matches, e.g. "+go.sum" keeps that file despite the go profile.
+func ParseIncludePatterns(raws []string, source Source) (includes, forced []Pattern, err error) {
+	for i, raw := range raws {
+		trimmed, isForced := strings.CutPrefix(raw, "+")
+		pattern, ok, err := parseLine(trimmed, source.at(i+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case !ok:
+		case isForced:
+			forced = append(forced, pattern)
+		default:
+			includes = append(includes, pattern)
+		}
+	}
+	return includes, forced, nil
+}
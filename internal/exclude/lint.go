@@ -0,0 +1,90 @@
+// Package exclude. lint validates the lines of ignore files before they silently do the wrong thing.
+package exclude
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// IssueKind classifies a problem found by linting an ignore file.
+type IssueKind string
+
+const (
+	IssueInvalid    IssueKind = "invalid"            // IssueInvalid patterns can't be parsed and are ignored.
+	IssueSuspicious IssueKind = "suspicious"         // IssueSuspicious patterns are valid but unlikely to mean what was written.
+	IssueUnused     IssueKind = "matches nothing"    // IssueUnused patterns match no path in the tree.
+	IssueShadowed   IssueKind = "shadowed"           // IssueShadowed patterns have no effect because of earlier patterns.
+	IssueDefault    IssueKind = "duplicates default" // IssueDefault patterns repeat one of the active default exclusions.
+)
+
+// Issue is a single problem found in an ignore file.
+type Issue struct {
+	Source  Source // Source is the file and line of the offending pattern.
+	Pattern string // Pattern is the raw pattern.
+	Kind    IssueKind
+	Message string
+}
+
+// String formats the issue as "file:line: pattern: kind: message".
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", i.Source, i.Pattern, i.Kind, i.Message)
+}
+
+// LintLines checks the lines of the ignore file at filePath on their own, without looking at the tree:
+// invalid syntax, "**" runs that are not a whole segment, and duplicates of the given default exclusions.
+// It returns the issues and the valid patterns, which can be checked against the tree afterwards.
+func LintLines(lines []string, filePath string, defaults []Pattern) (issues []Issue, patterns []Pattern) {
+	source := FileSource(filePath)
+	defaultSources := map[string]Source{}
+	for _, pattern := range defaults {
+		defaultSources[pattern.Raw] = pattern.Source
+	}
+
+	for i, raw := range lines {
+		lineSource := source.at(i + 1)
+		if strings.TrimSpace(raw) == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+
+		pattern, err := ParsePattern(filepath.ToSlash(raw))
+		if err != nil {
+			issues = append(issues, Issue{Source: lineSource, Pattern: raw, Kind: IssueInvalid, Message: err.Error()})
+			continue
+		}
+		pattern.Source = lineSource
+		patterns = append(patterns, pattern)
+
+		if segment, ok := pattern.starRun(); ok {
+			issues = append(issues, Issue{Source: lineSource, Pattern: raw, Kind: IssueSuspicious,
+				Message: fmt.Sprintf("%q is not a whole \"**\" segment, it matches like a single \"*\"", segment)})
+		}
+		if defaultSource, found := defaultSources[trimTrailingSpaces(raw)]; found {
+			issues = append(issues, Issue{Source: lineSource, Pattern: raw, Kind: IssueDefault,
+				Message: "already part of the " + defaultSource.String()})
+		}
+	}
+	return issues, patterns
+}
+
+// LintFileFS runs LintLines on the ignore file at the slash-separated name inside fsys. A missing file has no
+// issues. Sources record name as the file path.
+func LintFileFS(fsys fs.FS, name string, defaults []Pattern) (issues []Issue, patterns []Pattern, err error) {
+	lines, err := readPatternLinesFS(fsys, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	issues, patterns = LintLines(lines, name, defaults)
+	return issues, patterns, nil
+}
+
+// starRun returns the first glob segment holding "**" without being exactly "**", such as "***.mod" or "a**".
+func (p Pattern) starRun() (string, bool) {
+	for _, seg := range p.segments {
+		if seg.kind != segmentAnyDirs && strings.Contains(seg.glob, "**") {
+			return seg.glob, true
+		}
+	}
+	return "", false
+}
@@ -2,40 +2,80 @@
 package exclude
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
+// IgnoreFileName is the name of treeclip's own ignore file.
+const IgnoreFileName = ".treeclipignore"
+
 // LoadIgnorePatterns reads .treeclipignore from the given root path and returns a slice of patterns.
-func LoadIgnorePatterns(rootPath string) ([]string, error) {
-	var patterns []string
+// The file uses gitignore syntax, see Pattern.
+func LoadIgnorePatterns(rootPath string) ([]Pattern, error) {
+	return LoadPatternFile(filepath.Join(rootPath, IgnoreFileName))
+}
 
-	ignoreFilePath := filepath.Join(rootPath, ".treeclipignore")
+// LoadPatternFile reads a gitignore-style file. A missing file yields no patterns.
+func LoadPatternFile(filePath string) ([]Pattern, error) {
+	lines, err := readPatternLines(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePatterns(lines, FileSource(filePath))
+}
 
-	content, err := os.ReadFile(ignoreFilePath)
+// LoadPatternFileFS is LoadPatternFile for the slash-separated name inside fsys. Sources record name as the file path.
+func LoadPatternFileFS(fsys fs.FS, name string) ([]Pattern, error) {
+	lines, err := readPatternLinesFS(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePatterns(lines, FileSource(name))
+}
+
+// ScopePatterns limits patterns declared in the ignore file of dir (relative to the traversal root) to that subtree.
+func ScopePatterns(patterns []Pattern, dir string) []Pattern {
+	dir = filepath.ToSlash(dir)
+	if dir == "." {
+		dir = ""
+	}
+	for i := range patterns {
+		patterns[i].Dir = dir
+	}
+	return patterns
+}
+
+// readPatternLines returns the lines of a gitignore-style file, blank lines and comments included so
+// that line numbers are preserved. A missing file yields no lines.
+func readPatternLines(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	return splitPatternLines(filepath.Base(filePath), content, err)
+}
+
+// readPatternLinesFS is readPatternLines for the slash-separated name inside fsys.
+func readPatternLinesFS(fsys fs.FS, name string) ([]string, error) {
+	content, err := fs.ReadFile(fsys, name)
+	return splitPatternLines(path.Base(name), content, err)
+}
+
+// splitPatternLines splits the content of the pattern file named base, as read with error err.
+func splitPatternLines(base string, content []byte, err error) ([]string, error) {
 	if err != nil {
 		// File does not exist — not an error
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to read .treeclipignore: %w (ノಠ益ಠ)ノ", err)
+		return nil, fmt.Errorf("failed to read %s: %w (ノಠ益ಠ)ノ", base, err)
 	}
 
 	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Normalize slashes for cross-platform support
-		line = filepath.ToSlash(line)
-		patterns = append(patterns, line)
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
 	}
-
-	return patterns, nil
+	return lines, nil
 }
@@ -2,59 +2,178 @@
 package exclude
 
 import (
+	"path"
 	"path/filepath"
-	"strings"
+	"slices"
+	"sync"
 )
 
-var DefaultExclusions = []string{
-	"treeclip_output.txt",
-	"*.tmp", "*.temp", "*.exe", "*.sh",
-	".git", ".idea", ".DS_Store", "Thumbs.db",
-}
-
-// ShouldExclude checks if a file or directory should be excluded based on the exclude patterns. It supports exact, wildcard, relative file/folder name/path(s).
+// ShouldExclude checks if a file or directory should be excluded based on the exclude patterns.
+// Patterns use gitignore semantics (see Pattern) and are evaluated in order: the last matching pattern wins,
+// so a later "!pattern" re-includes a path excluded by an earlier one. Invalid patterns are ignored.
+// It is kept for compatibility: it parses the patterns on every call, prefer a Matcher when checking many paths.
+// name is the base name of relPath and is kept for compatibility with older callers. See Explain for the reason.
 func ShouldExclude(relPath, name string, isDir bool, patterns []string) bool {
 	// Normalize the relative path to use forward slashes
 	normalizedRelPath := filepath.ToSlash(relPath)
+	return compatMatcher(patterns).Explain(normalizedRelPath, isDir).Excluded
+}
+
+// lastCompat caches the matcher of the latest ShouldExclude call, callers usually pass the same patterns for a whole walk.
+var lastCompat struct {
+	sync.Mutex
+	raws    []string
+	matcher *Matcher
+}
+
+// compatMatcher returns the compiled matcher for raw patterns, reusing the previous one when they did not change.
+func compatMatcher(raws []string) *Matcher {
+	lastCompat.Lock()
+	defer lastCompat.Unlock()
 
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-		if pattern == "" {
-			continue
+	if lastCompat.matcher == nil || !slices.Equal(lastCompat.raws, raws) {
+		lastCompat.raws = slices.Clone(raws)
+		lastCompat.matcher = NewMatcher(nil)
+		for _, raw := range raws {
+			if pattern, ok, err := parseLine(raw, FlagSource("")); ok && err == nil {
+				lastCompat.matcher.Add(pattern)
+			}
 		}
+	}
+	return lastCompat.matcher
+}
 
-		// Normalize the pattern to use forward slashes
-		normalizedPattern := filepath.ToSlash(pattern)
+// Explain reports whether the slash-separated relPath is excluded by the parsed patterns and why, see Matcher.Explain.
+func Explain(relPath string, isDir bool, patterns []Pattern) Match {
+	return NewMatcher(patterns).Explain(relPath, isDir)
+}
 
-		// Check exact name match
-		if name == pattern || name == filepath.Base(normalizedPattern) {
-			return true
+// Matcher evaluates an ordered list of patterns with last-match-wins, without trying every pattern
+// on every path. Patterns are indexed once: literal names in a set, "*.ext" patterns by extension, literal anchored
+// paths in a trie, and the remaining precompiled globs are only tried while they could still beat the best match.
+type Matcher struct {
+	patterns []Pattern
+	names    map[string][]int // names indexes unanchored literal patterns by name.
+	exts     map[string][]int // exts indexes "ext:" and unanchored "*<suffix>" patterns by the extension of their suffix.
+	paths    *trieNode        // paths indexes anchored literal patterns by their path from the traversal root.
+	globs    []int            // globs holds every other pattern, in declaration order.
+}
+
+// NewMatcher compiles patterns, ordered from the lowest to the highest precedence.
+func NewMatcher(patterns []Pattern) *Matcher {
+	m := &Matcher{names: map[string][]int{}, exts: map[string][]int{}, paths: &trieNode{}}
+	m.Add(patterns...)
+	return m
+}
+
+// Add appends patterns with a higher precedence than the ones already added, e.g. those of a nested ignore file.
+func (m *Matcher) Add(patterns ...Pattern) {
+	for _, p := range patterns {
+		index := len(m.patterns)
+		m.patterns = append(m.patterns, p)
+
+		switch {
+		case p.exts != nil:
+			for _, ext := range p.exts {
+				m.exts[path.Ext(ext)] = append(m.exts[path.Ext(ext)], index)
+			}
+		case p.regex == nil && !p.Anchored && p.segments[0].kind == segmentSuffix && path.Ext(p.segments[0].literal) != "":
+			ext := path.Ext(p.segments[0].literal)
+			m.exts[ext] = append(m.exts[ext], index)
+		case !p.literal():
+			m.globs = append(m.globs, index)
+		case !p.Anchored:
+			name := p.segments[0].literal
+			m.names[name] = append(m.names[name], index)
+		default:
+			if key, ok := p.rootSegments(); ok {
+				m.paths.insert(key, index)
+			}
 		}
+	}
+}
+
+// Len returns the number of patterns in the matcher.
+func (m *Matcher) Len() int {
+	return len(m.patterns)
+}
 
-		// Check exact relative path match (both normalized)
-		if normalizedRelPath == normalizedPattern {
-			return true
+// LastMatch returns the last pattern matching the slash-separated relPath, or nil if none does.
+func (m *Matcher) LastMatch(relPath string, isDir bool) *Pattern {
+	return m.lastMatch(newPathInfo(relPath), isDir)
+}
+
+func (m *Matcher) lastMatch(info pathInfo, isDir bool) *Pattern {
+	best := -1
+	name := info.segments[len(info.segments)-1]
+	for _, i := range m.names[name] {
+		if i > best && m.patterns[i].matchPath(info, isDir) {
+			best = i
+		}
+	}
+	for _, i := range m.exts[path.Ext(name)] {
+		if i > best && m.patterns[i].matchPath(info, isDir) {
+			best = i
+		}
+	}
+	for _, i := range m.paths.lookup(info.segments) {
+		if i > best && m.patterns[i].matchPath(info, isDir) {
+			best = i
+		}
+	}
+	for j := len(m.globs) - 1; j >= 0 && m.globs[j] > best; j-- {
+		if m.patterns[m.globs[j]].matchPath(info, isDir) {
+			best = m.globs[j]
+			break
 		}
+	}
+
+	if best < 0 {
+		return nil
+	}
+	return &m.patterns[best]
+}
 
-		// Check wildcard pattern match against filename
-		if matched, _ := filepath.Match(normalizedPattern, name); matched {
-			return true
+// Explain reports whether the slash-separated relPath is excluded and why.
+// Like git, a path inside an excluded directory is excluded too (RuleParentDir), whatever its own patterns say.
+func (m *Matcher) Explain(relPath string, isDir bool) Match {
+	if relPath == "." || relPath == "" {
+		return Match{Path: relPath}
+	}
+
+	info := newPathInfo(relPath)
+	for n := 1; n < len(info.segments); n++ {
+		dir := info.parent(n)
+		if match := NewMatch(m.lastMatch(dir, true), dir.rel); match.Excluded {
+			match.Rule = RuleParentDir
+			return match
 		}
+	}
+	return NewMatch(m.lastMatch(info, isDir), relPath)
+}
 
-		// Check wildcard pattern match against relative path
-		if matched, _ := filepath.Match(normalizedPattern, normalizedRelPath); matched {
-			return true
+// MayMatchBelow returns the last pattern that is not negated and could match a path inside the slash-separated
+// directory dir, see Pattern.MayMatchBelow; nil if none could.
+func (m *Matcher) MayMatchBelow(dir string) *Pattern {
+	for i := len(m.patterns) - 1; i >= 0; i-- {
+		if !m.patterns[i].Negate && m.patterns[i].MayMatchBelow(dir) {
+			return &m.patterns[i]
 		}
+	}
+	return nil
+}
 
-		// For directories, check parent directories
-		if isDir {
-			pathParts := strings.Split(normalizedRelPath, "/")
-			for _, part := range pathParts {
-				if matched, _ := filepath.Match(normalizedPattern, part); matched {
-					return true
-				}
-			}
+// IncludeMatch returns the pattern deciding whether relPath is allowed when the matcher holds include patterns.
+// A path no pattern matches is decided by its closest matching parent directory; nil means nothing applies.
+func (m *Matcher) IncludeMatch(relPath string, isDir bool) *Pattern {
+	info := newPathInfo(relPath)
+	if last := m.lastMatch(info, isDir); last != nil {
+		return last
+	}
+	for n := len(info.segments) - 1; n > 0; n-- {
+		if last := m.lastMatch(info.parent(n), true); last != nil {
+			return last
 		}
 	}
-	return false
+	return nil
 }
@@ -0,0 +1,81 @@
+package exclude
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchPatterns is a few dozen patterns of the usual kinds: names, extensions, anchored paths and globs.
+var benchPatterns = []string{
+	".git", ".svn", ".hg", ".idea", ".vscode", ".DS_Store", "Thumbs.db", "*.swp", "*~", "*.tmp",
+	"node_modules/", "vendor/", "dist/", "build/", "target/", "bin/", "obj/", "coverage/", "__pycache__/", ".venv/",
+	"*.log", "*.pyc", "*.class", "*.o", "*.so", "*.exe", "*.dll", "*.min.js", "*.map", "go.sum",
+	"/docs/generated", "/internal/testdata/big", "api/**/*.pb.go", "**/fixtures/*.json", "web/static/**",
+	"!web/static/index.html", "cmd/*/main_gen.go", "**/*_mock.go", "re:_gen\\.go$", "ext:png,jpg,gif",
+}
+
+// benchPaths are paths of a typical tree, most of them matching nothing.
+func benchPaths() []string {
+	var paths []string
+	for i := range 50 {
+		dir := fmt.Sprintf("internal/pkg%d/sub%d", i, i%7)
+		paths = append(paths,
+			dir+"/handler.go", dir+"/handler_test.go", dir+"/types_mock.go", dir+"/README.md",
+			dir+"/fixtures/case.json", dir+"/logo.png", dir+"/debug.log", dir,
+		)
+	}
+	return paths
+}
+
+// BenchmarkShouldExclude checks every path with the compatibility wrapper, which reuses its compiled patterns.
+func BenchmarkShouldExclude(b *testing.B) {
+	paths := benchPaths()
+	b.ResetTimer()
+	for range b.N {
+		for _, p := range paths {
+			ShouldExclude(p, "", false, benchPatterns)
+		}
+	}
+}
+
+// BenchmarkMatcher compares trying every pattern on every path, as ShouldExclude used to, with the indexed Matcher.
+func BenchmarkMatcher(b *testing.B) {
+	patterns, err := ParsePatterns(benchPatterns, FlagSource("--exclude"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	paths := benchPaths()
+
+	b.Run("linear", func(b *testing.B) {
+		for range b.N {
+			for _, p := range paths {
+				for i := len(patterns) - 1; i >= 0; i-- {
+					if patterns[i].Match(p, false) {
+						break
+					}
+				}
+			}
+		}
+	})
+	b.Run("reparsed", func(b *testing.B) {
+		for range b.N {
+			for _, p := range paths {
+				parsed, _ := ParsePatterns(benchPatterns, FlagSource("--exclude"))
+				for i := len(parsed) - 1; i >= 0; i-- {
+					if parsed[i].Match(p, false) {
+						break
+					}
+				}
+			}
+		}
+	})
+	b.Run("matcher", func(b *testing.B) {
+		matcher := NewMatcher(patterns)
+		b.ResetTimer()
+		for range b.N {
+			for _, p := range paths {
+				matcher.LastMatch(p, false)
+			}
+		}
+	})
+}
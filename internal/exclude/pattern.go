@@ -0,0 +1,362 @@
+// Package exclude. pattern parses gitignore-style patterns and matches them against relative paths.
+package exclude
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pattern is a single parsed gitignore-style rule.
+//
+// The syntax follows gitignore(5):
+//
+// - "!" prefix negates the pattern and re-includes a previously excluded path
+//
+// - a trailing "/" only matches directories
+//
+// - a "/" at the beginning or in the middle anchors the pattern to the root, otherwise it matches at any depth
+//
+// - "*", "?" and "[...]" never match "/"; "**" as a whole segment matches any number of directories
+//
+// Two extended forms are accepted as well, see parseExtended: "re:<regexp>" and "ext:<ext>[,<ext>...]".
+type Pattern struct {
+	Raw      string // Raw is the pattern as it was written.
+	Negate   bool   // Negate is set for "!" patterns.
+	DirOnly  bool   // DirOnly is set for patterns with a trailing "/".
+	Anchored bool   // Anchored is set when the pattern is matched against the whole relative path.
+
+	// Dir is the directory, relative to the traversal root, of the file that declared the pattern.
+	// Like a nested .gitignore, the pattern only applies inside Dir and is matched relative to it.
+	Dir string
+	// Source records where the pattern was declared.
+	Source Source
+
+	segments  []segment      // segments holds the precompiled "/"-separated glob segments used for matching.
+	outerPath string         // outerPath is the traversal root relative to a declaring file that lives above it.
+	regex     *regexp.Regexp // regex is set for "re:" patterns.
+	exts      []string       // exts holds the dotted extensions of "ext:" patterns.
+}
+
+// ParsePattern parses a single gitignore-style line. Blank lines and comments must be filtered out by the caller.
+func ParsePattern(raw string) (Pattern, error) {
+	p := Pattern{Raw: raw}
+
+	line := trimTrailingSpaces(raw)
+	if strings.HasPrefix(line, "!") {
+		p.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if isExtended(line) {
+		return p, p.parseExtended(line)
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.HasPrefix(line, "/") {
+		p.Anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.Anchored = true
+	}
+	if line == "" {
+		return Pattern{}, fmt.Errorf("invalid pattern %q: empty pattern (ノಠ益ಠ)ノ", raw)
+	}
+
+	for _, segment := range strings.Split(line, "/") {
+		if segment == "" {
+			continue
+		}
+		// gitignore accepts both "[!...]" and "[^...]" for negated classes, path.Match only the latter
+		segment = strings.ReplaceAll(segment, "[!", "[^")
+		if _, err := path.Match(segment, ""); err != nil {
+			return Pattern{}, fmt.Errorf("invalid pattern %q: %w (ノಠ益ಠ)ノ", raw, err)
+		}
+		p.segments = append(p.segments, compileSegment(segment))
+	}
+	return p, nil
+}
+
+// PathPattern returns an anchored pattern matching exactly the slash-separated relative path rel, whose
+// characters are all taken literally.
+func PathPattern(rel string, source Source) Pattern {
+	p := Pattern{Raw: "/" + rel, Anchored: true, Source: source}
+	for _, name := range strings.Split(rel, "/") {
+		p.segments = append(p.segments, segment{glob: name, kind: segmentLiteral, literal: name})
+	}
+	return p
+}
+
+// Match reports whether the pattern matches the given slash-separated relative path.
+// Negation is not applied here; callers decide what a match means (see ShouldExclude).
+func (p Pattern) Match(relPath string, isDir bool) bool {
+	return p.matchPath(newPathInfo(relPath), isDir)
+}
+
+// matchPath is Match for a path that has already been split.
+func (p Pattern) matchPath(info pathInfo, isDir bool) bool {
+	if p.DirOnly && !isDir {
+		return false
+	}
+	segments := info.segments
+	if p.Dir != "" {
+		if len(info.rel) <= len(p.Dir) || info.rel[len(p.Dir)] != '/' || !strings.HasPrefix(info.rel, p.Dir) {
+			return false
+		}
+		segments = segments[strings.Count(p.Dir, "/")+1:]
+	}
+	if p.outerPath != "" {
+		segments = append(strings.Split(p.outerPath, "/"), segments...)
+	}
+	if p.regex != nil || p.exts != nil {
+		return p.matchExtended(segments, isDir)
+	}
+	if !p.Anchored {
+		return p.segments[0].match(segments[len(segments)-1])
+	}
+	return matchSegments(p.segments, segments)
+}
+
+// MayMatchBelow reports whether the pattern could match a path inside the slash-separated directory dir.
+// Only anchored globs name where they apply: a name, "re:" or "ext:" pattern reports false, since it could
+// match below any directory.
+func (p Pattern) MayMatchBelow(dir string) bool {
+	if !p.Anchored || p.regex != nil || p.exts != nil {
+		return false
+	}
+	segments := strings.Split(dir, "/")
+	if p.Dir != "" {
+		scope := strings.Split(p.Dir, "/")
+		if len(segments) < len(scope) {
+			return slices.Equal(segments, scope[:len(segments)])
+		}
+		if !slices.Equal(segments[:len(scope)], scope) {
+			return false
+		}
+		segments = segments[len(scope):]
+	}
+	if p.outerPath != "" {
+		segments = append(strings.Split(p.outerPath, "/"), segments...)
+	}
+	return matchPrefix(p.segments, segments)
+}
+
+// matchPrefix reports whether glob segments could match a path starting with the directory segments.
+func matchPrefix(pattern []segment, segments []string) bool {
+	for ; len(segments) > 0; segments = segments[1:] {
+		switch {
+		case len(pattern) == 0:
+			return false
+		case pattern[0].kind == segmentAnyDirs:
+			return true
+		case !pattern[0].match(segments[0]):
+			return false
+		}
+		pattern = pattern[1:]
+	}
+	return len(pattern) > 0
+}
+
+// ParsePatterns parses raw patterns declared by source, skipping blank lines and comments.
+// For file sources raws are the file's lines, and each pattern records its 1-based line number.
+// Invalid globs are skipped as they always have been, while an invalid "re:" or "ext:" pattern is reported
+// with its source.
+func ParsePatterns(raws []string, source Source) ([]Pattern, error) {
+	var patterns []Pattern
+	for i, raw := range raws {
+		pattern, ok, err := parseLine(raw, source.at(i+1))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// parseLine parses one line of a pattern list, see ParsePatterns. ok is false for lines that yield no pattern.
+func parseLine(raw string, source Source) (pattern Pattern, ok bool, err error) {
+	if strings.TrimSpace(raw) == "" || strings.HasPrefix(raw, "#") {
+		return Pattern{}, false, nil
+	}
+
+	// Normalize the pattern to use forward slashes
+	pattern, err = ParsePattern(filepath.ToSlash(raw))
+	if err != nil {
+		if isExtended(strings.TrimPrefix(raw, "!")) {
+			return Pattern{}, false, fmt.Errorf("%s: %w", source, err)
+		}
+		return Pattern{}, false, nil
+	}
+	pattern.Source = source
+	return pattern, true, nil
+}
+
+// Rule describes how a pattern matched a path.
+func (p Pattern) Rule() Rule {
+	switch {
+	case p.regex != nil:
+		return RuleRegex
+	case p.exts != nil:
+		return RuleExtension
+	case !p.literal():
+		return RuleWildcard
+	case p.Anchored:
+		return RuleRelativePath
+	default:
+		return RuleName
+	}
+}
+
+// literal reports whether the pattern is a glob without wildcards, so it only ever matches one name or path.
+func (p Pattern) literal() bool {
+	if p.regex != nil || p.exts != nil {
+		return false
+	}
+	for _, seg := range p.segments {
+		if seg.kind != segmentLiteral {
+			return false
+		}
+	}
+	return true
+}
+
+// rootSegments returns the path, relative to the traversal root, a literal anchored pattern matches.
+// ok is false when the pattern can't match anything below the root.
+func (p Pattern) rootSegments() (key []string, ok bool) {
+	for _, seg := range p.segments {
+		key = append(key, seg.literal)
+	}
+	if p.outerPath != "" {
+		outer := strings.Split(p.outerPath, "/")
+		if len(key) <= len(outer) || strings.Join(key[:len(outer)], "/") != p.outerPath {
+			return nil, false
+		}
+		key = key[len(outer):]
+	}
+	if p.Dir != "" {
+		key = append(strings.Split(p.Dir, "/"), key...)
+	}
+	return key, true
+}
+
+// matchSegments matches glob segments against path segments, expanding "**" to zero or more directories.
+func matchSegments(pattern []segment, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0].kind == segmentAnyDirs {
+			for len(pattern) > 0 && pattern[0].kind == segmentAnyDirs {
+				pattern = pattern[1:]
+			}
+			// A trailing "/**" matches everything inside, but not the directory itself
+			if len(pattern) == 0 {
+				return len(segments) > 0
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if !pattern[0].match(segments[0]) {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// segmentKind selects how a glob segment is matched, so common shapes avoid path.Match.
+type segmentKind uint8
+
+const (
+	segmentGlob    segmentKind = iota // segmentGlob is matched with path.Match.
+	segmentLiteral                    // segmentLiteral has no wildcards: "main.go".
+	segmentSuffix                     // segmentSuffix is a star followed by a literal: "*.go".
+	segmentPrefix                     // segmentPrefix is a literal followed by a star: "README*".
+	segmentAny                        // segmentAny is a single star: "*".
+	segmentAnyDirs                    // segmentAnyDirs is "**", any number of directories when anchored.
+)
+
+// segment is a precompiled glob segment.
+type segment struct {
+	glob    string
+	kind    segmentKind
+	literal string // literal is the fixed part of literal, suffix and prefix segments.
+}
+
+// compileSegment picks the cheapest way to match glob, which has already been validated.
+func compileSegment(glob string) segment {
+	const meta = `*?[\`
+	switch {
+	case glob == "**":
+		return segment{glob: glob, kind: segmentAnyDirs}
+	case strings.Trim(glob, "*") == "":
+		return segment{glob: glob, kind: segmentAny}
+	case !strings.ContainsAny(glob, meta):
+		return segment{glob: glob, kind: segmentLiteral, literal: glob}
+	case strings.HasPrefix(glob, "*") && !strings.ContainsAny(glob[1:], meta):
+		return segment{glob: glob, kind: segmentSuffix, literal: glob[1:]}
+	case strings.HasSuffix(glob, "*") && !strings.ContainsAny(glob[:len(glob)-1], meta):
+		return segment{glob: glob, kind: segmentPrefix, literal: glob[:len(glob)-1]}
+	default:
+		return segment{glob: glob, kind: segmentGlob}
+	}
+}
+
+// match reports whether the segment matches a single path element.
+func (s segment) match(name string) bool {
+	switch s.kind {
+	case segmentLiteral:
+		return name == s.literal
+	case segmentSuffix:
+		return strings.HasSuffix(name, s.literal)
+	case segmentPrefix:
+		return strings.HasPrefix(name, s.literal)
+	case segmentAny, segmentAnyDirs:
+		return true
+	default:
+		matched, _ := path.Match(s.glob, name)
+		return matched
+	}
+}
+
+// pathInfo is a slash-separated relative path split once for all the patterns it is matched against.
+type pathInfo struct {
+	rel      string
+	segments []string
+}
+
+func newPathInfo(rel string) pathInfo {
+	return pathInfo{rel: rel, segments: strings.Split(rel, "/")}
+}
+
+// parent returns the path made of the first n segments.
+func (p pathInfo) parent(n int) pathInfo {
+	length := n - 1
+	for _, seg := range p.segments[:n] {
+		length += len(seg)
+	}
+	return pathInfo{rel: p.rel[:length], segments: p.segments[:n]}
+}
+
+// trimTrailingSpaces removes trailing spaces unless they are escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
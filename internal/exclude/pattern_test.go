@@ -0,0 +1,105 @@
+package exclude
+
+import "testing"
+
+// TestExplainGitignore checks patterns against the results gitignore(5) documents, and git check-ignore gives.
+func TestExplainGitignore(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		// The last matching pattern wins, a "!" pattern re-includes
+		{"negation after", []string{"*.log", "!keep.log"}, "debug.log", false, true},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"negation at any depth", []string{"*.log", "!keep.log"}, "sub/keep.log", false, false},
+		{"negation before", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+
+		// A leading or middle "/" anchors to the root, otherwise the pattern matches at any depth
+		{"leading slash", []string{"/build"}, "build", true, true},
+		{"leading slash nested", []string{"/build"}, "src/build", true, false},
+		{"middle slash", []string{"doc/frotz"}, "doc/frotz", false, true},
+		{"middle slash nested", []string{"doc/frotz"}, "a/doc/frotz", false, false},
+		{"no slash nested", []string{"frotz"}, "a/b/frotz", false, true},
+
+		// A trailing "/" only matches directories
+		{"trailing slash dir", []string{"logs/"}, "logs", true, true},
+		{"trailing slash file", []string{"logs/"}, "logs", false, false},
+		{"trailing slash nested dir", []string{"logs/"}, "a/logs", true, true},
+
+		// "**"
+		{"leading ** at root", []string{"**/foo"}, "foo", false, true},
+		{"leading ** nested", []string{"**/foo"}, "a/b/foo", false, true},
+		{"leading ** path at root", []string{"**/foo/bar"}, "foo/bar", false, true},
+		{"leading ** path nested", []string{"**/foo/bar"}, "a/foo/bar", false, true},
+		{"trailing ** inside", []string{"abc/**"}, "abc/x", false, true},
+		{"trailing ** deep inside", []string{"abc/**"}, "abc/x/y", false, true},
+		{"trailing ** not the dir", []string{"abc/**"}, "abc", true, false},
+		{"middle ** zero dirs", []string{"a/**/b"}, "a/b", false, true},
+		{"middle ** several dirs", []string{"a/**/b"}, "a/x/y/b", false, true},
+		{"middle ** anchored", []string{"a/**/b"}, "x/a/b", false, false},
+
+		// "*" and "?" don't match "/"
+		{"star in dir", []string{"a/*.go"}, "a/x.go", false, true},
+		{"star not across dirs", []string{"a/*.go"}, "a/b/x.go", false, false},
+		{"question mark", []string{"fil?.txt"}, "file.txt", false, true},
+		{"question mark needs a char", []string{"fil?.txt"}, "fil.txt", false, false},
+
+		// Character classes, "[!...]" negates like "[^...]"
+		{"negated class", []string{"[!a]bc"}, "bbc", false, true},
+		{"negated class excludes", []string{"[!a]bc"}, "abc", false, false},
+		{"class", []string{"[ab]c"}, "bc", false, true},
+
+		// Escapes and trailing spaces
+		{"escaped hash", []string{`\#hash`}, "#hash", false, true},
+		{"escaped bang", []string{`\!bang`}, "!bang", false, true},
+		{"escaped trailing space", []string{`trail\ `}, "trail ", false, true},
+		{"trailing spaces trimmed", []string{"trail  "}, "trail", false, true},
+		{"comment", []string{"#hash"}, "#hash", false, false},
+
+		// A path inside an excluded directory can't be re-included
+		{"parent dir excluded", []string{"build/"}, "build/out/app.js", false, true},
+		{"parent dir not re-included", []string{"build/", "!build/keep.txt"}, "build/keep.txt", false, true},
+		{"contents re-included", []string{"build/*", "!build/keep.txt"}, "build/keep.txt", false, false},
+		{"contents excluded", []string{"build/*", "!build/keep.txt"}, "build/other.txt", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patterns, err := ParsePatterns(test.patterns, FileSource(".gitignore"))
+			if err != nil {
+				t.Fatalf("ParsePatterns(%q): %v", test.patterns, err)
+			}
+			match := Explain(test.path, test.isDir, patterns)
+			if match.Excluded != test.excluded {
+				t.Errorf("%q excludes %s (dir %t) = %t, want %t", test.patterns, test.path, test.isDir, match.Excluded, test.excluded)
+			}
+		})
+	}
+}
+
+// TestExplainRule checks the rule and source reported for the deciding pattern.
+func TestExplainRule(t *testing.T) {
+	patterns, err := ParsePatterns([]string{"node_modules", "/docs/api.md", "*.log", "build/"}, FileSource(".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		rule  Rule
+		line  int
+	}{
+		{"web/node_modules", true, RuleName, 1},
+		{"docs/api.md", false, RuleRelativePath, 2},
+		{"logs/app.log", false, RuleWildcard, 3},
+		{"build/out/app.js", false, RuleParentDir, 4},
+	}
+	for _, test := range tests {
+		match := Explain(test.path, test.isDir, patterns)
+		if !match.Excluded || match.Rule != test.rule || match.Pattern.Source.Line != test.line {
+			t.Errorf("Explain(%s) = %+v, want excluded by line %d with rule %q", test.path, match, test.line, test.rule)
+		}
+	}
+}
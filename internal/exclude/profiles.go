@@ -0,0 +1,136 @@
+// Package exclude. profiles holds the default exclusions, split into named per-ecosystem profiles.
+package exclude
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Profile is a named set of default exclusions for one ecosystem.
+type Profile struct {
+	Name     string
+	Markers  []string // Markers are root-level file names or globs whose presence selects the profile.
+	Patterns []string
+}
+
+// CommonProfile is always active unless default exclusions are disabled: VCS metadata, editor and OS files,
+// and temporary files. treeclip's own output file is excluded apart from the profiles, it can't be disabled.
+var CommonProfile = Profile{
+	Name: "common",
+	Patterns: []string{
+		".git", ".hg", ".svn", ".idea", ".vscode", ".DS_Store", "Thumbs.db",
+		"*.tmp", "*.temp", "*.swp", "*.exe",
+		"treeclip_output.txt",
+	},
+}
+
+// Profiles are the ecosystem profiles, selected by DetectProfilesFS or by name.
+var Profiles = []Profile{
+	{
+		Name:     "go",
+		Markers:  []string{"go.mod", "go.work"},
+		Patterns: []string{"vendor/", "go.sum", "go.work.sum", "*.test", "*.prof", "coverage.out"},
+	},
+	{
+		Name:    "node",
+		Markers: []string{"package.json"},
+		Patterns: []string{
+			"node_modules/", "dist/", "coverage/", ".next/", ".nuxt/", ".turbo/", ".parcel-cache/",
+			"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "*.min.js", "*.min.css", "*.map",
+		},
+	},
+	{
+		Name:    "python",
+		Markers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", "Pipfile"},
+		Patterns: []string{
+			"__pycache__/", "*.pyc", "*.pyo", ".venv/", "venv/", ".tox/", ".mypy_cache/", ".pytest_cache/",
+			".ruff_cache/", "*.egg-info/", "dist/", "build/", "poetry.lock", "Pipfile.lock",
+		},
+	},
+	{
+		Name:     "rust",
+		Markers:  []string{"Cargo.toml"},
+		Patterns: []string{"target/", "Cargo.lock"},
+	},
+	{
+		Name:     "java",
+		Markers:  []string{"pom.xml", "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+		Patterns: []string{"target/", "build/", ".gradle/", "out/", "*.class", "*.jar", "*.war"},
+	},
+	{
+		Name:     "dotnet",
+		Markers:  []string{"*.sln", "*.csproj", "*.fsproj", "*.vbproj", "global.json"},
+		Patterns: []string{"bin/", "obj/", ".vs/", "*.dll", "*.pdb", "*.user"},
+	},
+	{
+		Name:     "terraform",
+		Markers:  []string{"*.tf"},
+		Patterns: []string{".terraform/", "*.tfstate", "*.tfstate.*", "*.tfplan", ".terraform.lock.hcl"},
+	},
+}
+
+// LookupProfile returns the profile with the given name, CommonProfile included.
+func LookupProfile(name string) (Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == CommonProfile.Name {
+		return CommonProfile, nil
+	}
+	for _, profile := range Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q, available: %s (ノಠ益ಠ)ノ", name, strings.Join(ProfileNames(), ", "))
+}
+
+// ProfileNames lists the names of Profiles.
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for _, profile := range Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// DetectProfilesFS returns the Profiles whose marker files exist directly in the root of fsys.
+func DetectProfilesFS(fsys fs.FS) ([]Profile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect profiles: %w (ノಠ益ಠ)ノ", err)
+	}
+
+	var detected []Profile
+	for _, profile := range Profiles {
+		if hasMarker(entries, profile.Markers) {
+			detected = append(detected, profile)
+		}
+	}
+	return detected, nil
+}
+
+// ProfilePatterns parses the patterns of the given profiles, in order. Each pattern's Source names its profile.
+func ProfilePatterns(profiles []Profile) ([]Pattern, error) {
+	var patterns []Pattern
+	for _, profile := range profiles {
+		profilePatterns, err := ParsePatterns(profile.Patterns, DefaultSource(profile.Name))
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, profilePatterns...)
+	}
+	return patterns, nil
+}
+
+// hasMarker reports whether any directory entry matches one of the marker names or globs.
+func hasMarker(entries []fs.DirEntry, markers []string) bool {
+	for _, entry := range entries {
+		for _, marker := range markers {
+			if matched, _ := path.Match(marker, entry.Name()); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// Package exclude. source describes where patterns come from and why they matched, for check-ignore style reports.
+package exclude
+
+import "fmt"
+
+// SourceKind identifies the kind of place a pattern was declared in.
+type SourceKind int
+
+const (
+	SourceFlag    SourceKind = iota // SourceFlag patterns come from a command line flag.
+	SourceFile                      // SourceFile patterns come from an ignore or include file.
+	SourceDefault                   // SourceDefault patterns are treeclip's built-in default exclusions, see Profile.
+	SourceOutput                    // SourceOutput patterns exclude the file treeclip writes its output to.
+)
+
+// Source records where a pattern was declared.
+type Source struct {
+	Kind SourceKind
+	Name string // Name is the flag name (e.g. "--exclude"), the file path, the profile name or the output file.
+	Line int    // Line is the 1-based line number for file sources.
+}
+
+// FlagSource is the Source of patterns passed through the named flag.
+func FlagSource(name string) Source {
+	return Source{Kind: SourceFlag, Name: name}
+}
+
+// FileSource is the Source of patterns read from the file at path.
+func FileSource(path string) Source {
+	return Source{Kind: SourceFile, Name: path}
+}
+
+// DefaultSource is the Source of the default exclusions of the named profile.
+func DefaultSource(profile string) Source {
+	return Source{Kind: SourceDefault, Name: profile}
+}
+
+// OutputSource is the Source of the pattern excluding the output file at path.
+func OutputSource(path string) Source {
+	return Source{Kind: SourceOutput, Name: path}
+}
+
+// String formats the source as "flag --exclude", "path:line", "default exclusions (go profile)" or
+// "output file treeclip_temp.txt".
+func (s Source) String() string {
+	switch s.Kind {
+	case SourceOutput:
+		return "output file " + s.Name
+	case SourceFile:
+		return fmt.Sprintf("%s:%d", s.Name, s.Line)
+	case SourceDefault:
+		if s.Name == "" {
+			return "default exclusions"
+		}
+		return fmt.Sprintf("default exclusions (%s profile)", s.Name)
+	default:
+		if s.Name == "" {
+			return "command line"
+		}
+		return "flag " + s.Name
+	}
+}
+
+// at returns the source of the pattern on the given line. Only file sources track lines.
+func (s Source) at(line int) Source {
+	if s.Kind == SourceFile {
+		s.Line = line
+	}
+	return s
+}
+
+// Rule names the matching rule that applied to a path.
+type Rule string
+
+const (
+	RuleName          Rule = "name"           // RuleName patterns match a file or folder name at any depth.
+	RuleRelativePath  Rule = "relative path"  // RuleRelativePath patterns match an exact path relative to their directory.
+	RuleWildcard      Rule = "wildcard"       // RuleWildcard patterns use "*", "?", "[...]" or "**".
+	RuleRegex         Rule = "regex"          // RuleRegex patterns are "re:" regular expressions.
+	RuleExtension     Rule = "extension"      // RuleExtension patterns are "ext:" extension classes.
+	RuleParentDir     Rule = "parent dir"     // RuleParentDir means a parent directory is excluded, so everything below it is too.
+	RuleNotIncluded   Rule = "not included"   // RuleNotIncluded means include patterns are set and none allows the path.
+	RuleForcedInclude Rule = "forced include" // RuleForcedInclude means a "+" include pattern overrode a default exclusion.
+)
+
+// Match explains the outcome for a single path.
+type Match struct {
+	Excluded bool
+	Pattern  *Pattern // Pattern is the deciding pattern, nil when no pattern matched.
+	Rule     Rule
+	Path     string // Path is the path Pattern matched: the queried path or one of its parent directories.
+}
+
+// NewMatch builds the Match decided by pattern p for matchedPath. A nil p yields a Match that excludes nothing.
+func NewMatch(p *Pattern, matchedPath string) Match {
+	if p == nil {
+		return Match{Path: matchedPath}
+	}
+	return Match{Excluded: !p.Negate, Pattern: p, Rule: p.Rule(), Path: matchedPath}
+}
@@ -0,0 +1,38 @@
+// Package exclude. trie indexes literal anchored patterns by path segment.
+package exclude
+
+// trieNode is a node of a path trie, keyed by one path segment per level.
+type trieNode struct {
+	children map[string]*trieNode
+	patterns []int // patterns holds the indexes of the patterns whose path ends at this node.
+}
+
+// insert records the pattern at index under the given path segments.
+func (n *trieNode) insert(segments []string, index int) {
+	node := n
+	for _, seg := range segments {
+		if node.children == nil {
+			node.children = map[string]*trieNode{}
+		}
+		child, ok := node.children[seg]
+		if !ok {
+			child = &trieNode{}
+			node.children[seg] = child
+		}
+		node = child
+	}
+	node.patterns = append(node.patterns, index)
+}
+
+// lookup returns the indexes of the patterns recorded for exactly the given path segments.
+func (n *trieNode) lookup(segments []string) []int {
+	node := n
+	for _, seg := range segments {
+		child, ok := node.children[seg]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node.patterns
+}
@@ -0,0 +1,88 @@
+// Package git. changes lists the files git reports as changed, staged or untracked, using the local git binary.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Selection picks files by their git state. The selected files are the union of every enabled state.
+type Selection struct {
+	ChangedSince string // ChangedSince selects files that differ between this revision and the working tree.
+	Staged       bool   // Staged selects files with changes in the index.
+	Unstaged     bool   // Unstaged selects files with changes in the working tree that are not staged.
+	Untracked    bool   // Untracked selects files git doesn't track, without the ignored ones.
+}
+
+// Empty reports whether no state is selected.
+func (s Selection) Empty() bool {
+	return s == Selection{}
+}
+
+// ChangedFiles returns the files under dir in the states of sel, as sorted slash-separated paths relative to dir.
+// Deleted files are left out since there is nothing to read. It fails when dir is not inside a git repository.
+func ChangedFiles(dir string, sel Selection) ([]string, error) {
+	if _, ok := FindRepo(dir); !ok {
+		return nil, fmt.Errorf("%s is not inside a git repository, git file selection needs one (ノಠ益ಠ)ノ", dir)
+	}
+
+	var commands [][]string
+	diff := []string{"diff", "--name-only", "-z", "--relative", "--diff-filter=d"}
+	if sel.ChangedSince != "" {
+		// Resolved first, so that a value starting with "-" can't pass for an option of git diff
+		out, err := run(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", sel.ChangedSince+"^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("unknown revision %q for --changed-since (ノಠ益ಠ)ノ", sel.ChangedSince)
+		}
+		commands = append(commands, append(slices.Clone(diff), strings.TrimSpace(string(out)), "--"))
+	}
+	if sel.Staged {
+		commands = append(commands, append(slices.Clone(diff), "--cached"))
+	}
+	if sel.Unstaged {
+		commands = append(commands, diff)
+	}
+	if sel.Untracked {
+		commands = append(commands, []string{"ls-files", "--others", "--exclude-standard", "-z"})
+	}
+
+	var files []string
+	for _, args := range commands {
+		out, err := run(dir, args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range strings.Split(string(out), "\x00") {
+			if file != "" {
+				files = append(files, file)
+			}
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// run runs the git binary in dir and returns its standard output. Failures carry git's own error message.
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("git binary not found in PATH (ノಠ益ಠ)ノ")
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s (ノಠ益ಠ)ノ", args[0], message)
+	}
+	return out, nil
+}
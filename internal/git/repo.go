@@ -0,0 +1,110 @@
+// Package git - git provides read-only helpers for locating a git repository and reading its configuration.
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo describes a git repository found on disk.
+type Repo struct {
+	TopLevel string // TopLevel is the working tree root.
+	GitDir   string // GitDir is the repository's .git directory.
+}
+
+// FindRepo walks up from dir until it finds a .git directory or a .git file (worktrees, submodules).
+// ok is false when dir is not inside a git repository.
+func FindRepo(dir string) (repo Repo, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return Repo{}, false
+	}
+
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return Repo{TopLevel: dir, GitDir: dotGit}, true
+			}
+			if gitDir, ok := readGitFile(dotGit); ok {
+				return Repo{TopLevel: dir, GitDir: gitDir}, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Repo{}, false
+		}
+		dir = parent
+	}
+}
+
+// CommonDir returns the directory holding the repository-wide files such as info/exclude.
+// It differs from GitDir only for linked worktrees.
+func (r Repo) CommonDir() string {
+	content, err := os.ReadFile(filepath.Join(r.GitDir, "commondir"))
+	if err != nil {
+		return r.GitDir
+	}
+
+	commonDir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(r.GitDir, commonDir)
+	}
+	return commonDir
+}
+
+// RelPath returns dir relative to the working tree root, slash-separated.
+func (r Repo) RelPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(r.TopLevel, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside of the repository at %s (ノಠ益ಠ)ノ", dir, r.TopLevel)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// ExcludesFile returns the path of the global excludes file (core.excludesFile).
+// It asks the local git binary first and falls back to git's default location, $XDG_CONFIG_HOME/git/ignore.
+func ExcludesFile(dir string) string {
+	cmd := exec.Command("git", "config", "--path", "--get", "core.excludesFile")
+	cmd.Dir = dir
+	if out, err := cmd.Output(); err == nil {
+		if path := strings.TrimSpace(string(out)); path != "" {
+			return path
+		}
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
+
+// readGitFile resolves a "gitdir: <path>" .git file into the directory it points to.
+func readGitFile(dotGit string) (string, bool) {
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", false
+	}
+
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !found {
+		return "", false
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(dotGit), gitDir)
+	}
+	return gitDir, true
+}
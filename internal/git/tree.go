@@ -0,0 +1,176 @@
+// Package git. tree exposes the tree object of a revision as a read-only fs.FS, without checking it out.
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seyedali-dev/treeclip/internal/memfs"
+)
+
+// TreeFS is the file tree of a commit, or of a directory inside it, as listed by git ls-tree.
+// File contents are read on demand through a single "git cat-file --batch" process, so it must be closed.
+// Submodules are left out; symlinks have fs.ModeSymlink, their target is read with ReadLink and is never resolved.
+type TreeFS struct {
+	Rev    string // Rev is the revision as given, e.g. a tag.
+	Commit string // Commit is the full hash Rev resolved to.
+	Prefix string // Prefix is the slash-separated directory of the commit the tree starts at, "." for the whole commit.
+
+	*memfs.FS
+	cat *catFile
+}
+
+// OpenTree resolves rev in the repository holding dir and lists the tree of the commit below dir.
+func OpenTree(dir, rev string) (*TreeFS, error) {
+	repo, ok := FindRepo(dir)
+	if !ok {
+		return nil, fmt.Errorf("%s is not inside a git repository, --rev needs one (ノಠ益ಠ)ノ", dir)
+	}
+	out, err := run(repo.TopLevel, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q (ノಠ益ಠ)ノ", rev)
+	}
+	commit := strings.TrimSpace(string(out))
+
+	out, err = run(repo.TopLevel, "show", "-s", "--format=%ct", commit)
+	if err != nil {
+		return nil, err
+	}
+	seconds, _ := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	modTime := time.Unix(seconds, 0)
+
+	prefix, err := repo.RelPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	treeish := commit
+	if prefix != "." {
+		treeish = commit + ":" + prefix
+	}
+	out, err = run(repo.TopLevel, "ls-tree", "-r", "-t", "-z", "-l", treeish)
+	if err != nil {
+		return nil, fmt.Errorf("%s does not exist in %s (ノಠ益ಠ)ノ", prefix, rev)
+	}
+
+	t := &TreeFS{Rev: rev, Commit: commit, Prefix: prefix}
+	// Blobs and symlink targets are read from the object database when opened
+	t.FS = memfs.New(modTime, func(entry *memfs.Entry) ([]byte, error) {
+		return t.cat.read(entry.Ref)
+	})
+	for _, line := range strings.Split(string(out), "\x00") {
+		if entry, name, ok := parseTreeLine(line, modTime); ok {
+			t.Add(name, entry)
+		}
+	}
+	t.Index()
+
+	if t.cat, err = startCatFile(repo.TopLevel); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Name formats the path p of the tree in git's "rev:path" notation.
+func (t *TreeFS) Name(p string) string {
+	return t.Rev + ":" + path.Join(t.Prefix, p)
+}
+
+// Close stops the cat-file process.
+func (t *TreeFS) Close() error {
+	return t.cat.close()
+}
+
+// parseTreeLine parses a "<mode> <type> <object> <size>\t<path>" line of git ls-tree -l.
+// Entries get the commit time, git doesn't record per-file times.
+func parseTreeLine(line string, modTime time.Time) (entry *memfs.Entry, name string, ok bool) {
+	meta, name, found := strings.Cut(line, "\t")
+	fields := strings.Fields(meta)
+	if !found || len(fields) != 4 {
+		return nil, "", false
+	}
+
+	var mode fs.FileMode
+	switch fields[0] {
+	case "040000":
+		mode = fs.ModeDir | 0o755
+	case "100755":
+		mode = 0o755
+	case "100644":
+		mode = 0o644
+	case "120000":
+		mode = fs.ModeSymlink | 0o777
+	default: // submodules
+		return nil, "", false
+	}
+	size, _ := strconv.ParseInt(fields[3], 10, 64)
+	entry = memfs.NewEntry(path.Base(name), mode, size, modTime)
+	entry.Ref = fields[2]
+	return entry, name, true
+}
+
+// catFile is a running "git cat-file --batch" process. Reads are serialized.
+type catFile struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+func startCatFile(dir string) (*catFile, error) {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = dir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w (ノಠ益ಠ)ノ", err)
+	}
+	return &catFile{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// read returns the content of the object oid.
+func (c *catFile) read(oid string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintln(c.stdin, oid); err != nil {
+		return nil, err
+	}
+	header, err := c.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	// "<oid> <type> <size>" or "<oid> missing"
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("git cat-file: %s", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %s", strings.TrimSpace(header))
+	}
+
+	content := make([]byte, size+1) // the content is followed by a newline
+	if _, err := io.ReadFull(c.stdout, content); err != nil {
+		return nil, err
+	}
+	return content[:size], nil
+}
+
+func (c *catFile) close() error {
+	_ = c.stdin.Close()
+	return c.cmd.Wait()
+}
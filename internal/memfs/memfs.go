@@ -0,0 +1,195 @@
+// Package memfs serves a file tree indexed in memory as a read-only fs.FS, such as the entries of a tar archive
+// or the tree of a git commit. File contents are either kept in memory or loaded on demand.
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS is a file tree built with Add and then Index. Symlinks have fs.ModeSymlink, their target is read with
+// ReadLink and is never resolved.
+type FS struct {
+	entries  map[string]*Entry
+	children map[string][]fs.DirEntry
+	load     func(entry *Entry) ([]byte, error)
+}
+
+// New returns an FS holding only its root directory. load reads the content of the files and symlinks whose
+// Data is not kept in memory; it may be nil when every entry keeps its Data.
+func New(modTime time.Time, load func(entry *Entry) ([]byte, error)) *FS {
+	return &FS{
+		entries:  map[string]*Entry{".": NewEntry(".", fs.ModeDir|0o755, 0, modTime)},
+		children: map[string][]fs.DirEntry{},
+		load:     load,
+	}
+}
+
+// Add stores the entry at the slash-separated name, adding its missing parent directories. An entry already
+// stored at name is replaced.
+func (f *FS) Add(name string, entry *Entry) {
+	f.entries[name] = entry
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, found := f.entries[dir]; found {
+			break
+		}
+		f.entries[dir] = NewEntry(path.Base(dir), fs.ModeDir|0o755, 0, entry.modTime)
+	}
+}
+
+// Lookup returns the entry stored at name.
+func (f *FS) Lookup(name string) (*Entry, bool) {
+	entry, found := f.entries[name]
+	return entry, found
+}
+
+// Index lists the entries of every directory, sorted by name. It must be called once every entry was added.
+func (f *FS) Index() {
+	for name, entry := range f.entries {
+		if name != "." {
+			f.children[path.Dir(name)] = append(f.children[path.Dir(name)], entry)
+		}
+	}
+	for _, children := range f.children {
+		slices.SortFunc(children, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	}
+}
+
+// Open implements fs.FS.
+func (f *FS) Open(name string) (fs.File, error) {
+	entry, err := f.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return &dir{entry: entry, children: f.children[name]}, nil
+	}
+	content, err := f.content(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{entry: entry, Reader: bytes.NewReader(content)}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := f.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return slices.Clone(f.children[name]), nil
+}
+
+// ReadLink returns the target of the symlink name.
+func (f *FS) ReadLink(name string) (string, error) {
+	entry, err := f.lookup("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if entry.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := f.content(entry)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(target), nil
+}
+
+// Stat implements fs.StatFS.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.lookup("stat", name)
+}
+
+func (f *FS) lookup(op, name string) (*Entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	entry, found := f.entries[name]
+	if !found {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+// content returns the content of a file or the target of a symlink, from memory or through load.
+func (f *FS) content(entry *Entry) ([]byte, error) {
+	switch {
+	case entry.Err != nil:
+		return nil, entry.Err
+	case entry.Data != nil || f.load == nil:
+		return entry.Data, nil
+	}
+	return f.load(entry)
+}
+
+// Entry is a file, directory or symlink of an FS. It is both its fs.FileInfo and its fs.DirEntry.
+type Entry struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+
+	Data []byte // Data is the content of a file or the target of a symlink, when it is kept in memory.
+	Ref  string // Ref locates the content for the FS's load function when Data is not kept, e.g. a git object id.
+	Err  error  // Err is returned when reading an entry whose content is not available, e.g. not kept.
+}
+
+// NewEntry returns the entry of a file, directory or symlink named name.
+func NewEntry(name string, mode fs.FileMode, size int64, modTime time.Time) *Entry {
+	return &Entry{name: name, mode: mode, size: size, modTime: modTime}
+}
+
+func (e *Entry) Name() string               { return e.name }
+func (e *Entry) Size() int64                { return e.size }
+func (e *Entry) Mode() fs.FileMode          { return e.mode }
+func (e *Entry) ModTime() time.Time         { return e.modTime }
+func (e *Entry) IsDir() bool                { return e.mode.IsDir() }
+func (e *Entry) Sys() any                   { return nil }
+func (e *Entry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *Entry) Info() (fs.FileInfo, error) { return e, nil }
+
+// file is an opened file.
+type file struct {
+	entry *Entry
+	*bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *file) Close() error               { return nil }
+
+// dir is an opened directory.
+type dir struct {
+	entry    *Entry
+	children []fs.DirEntry
+	offset   int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *dir) Close() error               { return nil }
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.children[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.children)
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.offset += len(rest)
+	return slices.Clone(rest), nil
+}
@@ -1,21 +1,123 @@
-// Package output. formatter provides file header and separator formatting.
+// Package output. formatter lays out the output document: its notes, the file entries and the tree section.
 package output
 
 import (
 	"fmt"
 	"io"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/pkg/utils"
 )
 
-// WriteHeader adds the file header to writer.
-func WriteHeader(file io.Writer, relPath string) {
-	if _, err := fmt.Fprintf(file, "==> %s\n", relPath); err != nil {
-		panic(fmt.Sprintf("❌🪲  [ERROR] failed to write to file: %v", err))
+// Formatter lays out an output document. BeginDocument and EndDocument surround it, every file entry is written
+// between BeginFile and EndFile, and a formatter may keep state between the two calls.
+type Formatter interface {
+	// BeginDocument starts the document with notes about it, such as "📁Paths are relative to /src".
+	BeginDocument(file io.Writer, notes []string)
+	EndDocument(file io.Writer)
+	// BeginRoot starts the entries reached through one of several roots, such as "cmd/".
+	BeginRoot(file io.Writer, root string)
+	// BeginFile writes the start of an entry. Its content, if it has any, is written next as is.
+	BeginFile(file io.Writer, entry FileEntry)
+	EndFile(file io.Writer)
+	// Tree writes a listing of the files, see WriteTree.
+	Tree(file io.Writer, root *TreeNode)
+}
+
+// Scanner is a Formatter that lays an entry out from its whole content, such as Markdown choosing a fence longer
+// than any run of backticks in it. When a content is too large to hold in memory, Scan reads it right before
+// BeginFile, whose entry then has no Content, and the content is read once more to be written.
+type Scanner interface {
+	Formatter
+	Scan(content io.Reader) error
+}
+
+// FileEntry is a file of the output document.
+type FileEntry struct {
+	Path    string // Path is the slash-separated path of the file.
+	Target  string // Target is set for a symlink listed instead of followed, an entry without content.
+	Detail  string // Detail ends the header, e.g. ":120-240" for some lines or " [not followed]" for a symlink.
+	Content []byte // Content is written between BeginFile and EndFile, unless there is a Note.
+	Note    string // Note replaces the content, e.g. "[binary, 1.2 MB, skipped]", see Skipped and Placeholder.
+}
+
+// header returns "path -> target detail", the target only for symlinks.
+func (e FileEntry) header() string {
+	if e.Target != "" {
+		return e.Path + " -> " + e.Target + e.Detail
+	}
+	return e.Path + e.Detail
+}
+
+// Formats are the names NewFormatter accepts.
+var Formats = []string{"text", "md"}
+
+// NewFormatter returns the formatter of the named format: "text" writes "==> path" headers and the contents as
+// they are, "md" (or "markdown") writes Markdown, see Markdown.
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case "", "text":
+		return Text{}, nil
+	case "md", "markdown":
+		return &Markdown{}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, expected one of %s (ノಠ益ಠ)ノ", format, strings.Join(Formats, ", "))
+}
+
+// Text is the plain text format:
+//
+//	// 💡Paths are displayed in Unix-style format (forward slashes)
+//	==> cmd/run.go
+//	package cmd
+//	...
+//
+//	==> assets/logo.png
+//	[binary, 1.2 MB, skipped]
+type Text struct{}
+
+// BeginDocument writes every note as a "// note" line.
+func (Text) BeginDocument(file io.Writer, notes []string) {
+	for _, note := range notes {
+		writeString(file, "// "+note+"\n")
+	}
+}
+
+func (Text) EndDocument(io.Writer) {}
+
+// BeginRoot writes a line such as "// 📂Root cmd/".
+func (Text) BeginRoot(file io.Writer, root string) {
+	writeString(file, fmt.Sprintf("// 📂Root %s\n", root))
+}
+
+// BeginFile writes the "==> path" header and the note replacing the content, if any.
+func (Text) BeginFile(file io.Writer, entry FileEntry) {
+	writeString(file, "==> "+entry.header()+"\n")
+	if entry.Note != "" {
+		writeString(file, entry.Note+"\n")
 	}
 }
 
-// WriteSeparator writes an empty line as separator.
-func WriteSeparator(file io.Writer) {
-	if _, err := fmt.Fprintln(file); err != nil {
+// EndFile writes an empty line as separator.
+func (Text) EndFile(file io.Writer) {
+	writeString(file, "\n")
+}
+
+func (Text) Tree(file io.Writer, root *TreeNode) {
+	WriteTree(file, root)
+}
+
+// Skipped returns a note such as "[named pipe, skipped]" to write in place of a file's content.
+func Skipped(reason string) string {
+	return fmt.Sprintf("[%s, skipped]", reason)
+}
+
+// Placeholder returns a note such as "[binary, 1.2 MB, skipped]" to write in place of a file's content.
+func Placeholder(reason string, size int64) string {
+	return fmt.Sprintf("[%s, %s, skipped]", reason, utils.FormatBytes(size))
+}
+
+func writeString(file io.Writer, s string) {
+	if _, err := io.WriteString(file, s); err != nil {
 		panic(fmt.Sprintf("❌🪲  [ERROR] failed to write to file: %v", err))
 	}
 }
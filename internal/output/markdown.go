@@ -0,0 +1,199 @@
+// Package output. markdown writes the output document as Markdown, a fenced code block per file.
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Markdown is the Markdown format: notes as a list, a "### path" heading per file and its content in a fenced
+// code block tagged with the language of the file. The fence is made longer than any run of backticks in the
+// content, so files holding Markdown code blocks themselves stay intact.
+//
+//	### cmd/run.go
+//
+//	```go
+//	package cmd
+//	...
+//	```
+type Markdown struct {
+	fence   string       // fence is the fence of the open code block, empty outside of one.
+	newline bool         // newline is false when the content of the open code block doesn't end with a newline.
+	scanned *contentScan // scanned is the content Scan read for the next BeginFile.
+}
+
+// BeginDocument writes the notes as a list.
+func (m *Markdown) BeginDocument(file io.Writer, notes []string) {
+	for _, note := range notes {
+		writeString(file, "- "+note+"\n")
+	}
+	if len(notes) > 0 {
+		writeString(file, "\n")
+	}
+}
+
+func (m *Markdown) EndDocument(io.Writer) {}
+
+// BeginRoot writes a "## 📂Root cmd/" heading.
+func (m *Markdown) BeginRoot(file io.Writer, root string) {
+	writeString(file, fmt.Sprintf("## 📂Root %s\n\n", root))
+}
+
+// BeginFile writes the "### path" heading, then the note or the opening fence of the content's code block.
+func (m *Markdown) BeginFile(file io.Writer, entry FileEntry) {
+	writeString(file, "### "+entry.header()+"\n\n")
+	switch {
+	case entry.Note != "":
+		writeString(file, entry.Note+"\n\n")
+	case entry.Target == "":
+		scan := m.scanned
+		if scan == nil {
+			scan = &contentScan{}
+			_, _ = scan.Write(entry.Content)
+		}
+		m.fence = scan.fence()
+		m.newline = scan.size == 0 || scan.last == '\n'
+		writeString(file, m.fence+Language(entry.Path, scan.head)+"\n")
+	}
+	m.scanned = nil
+}
+
+// Scan reads a content streamed after the next BeginFile, to fence it.
+func (m *Markdown) Scan(content io.Reader) error {
+	m.scanned = &contentScan{}
+	_, err := io.Copy(m.scanned, content)
+	return err
+}
+
+// EndFile closes the code block, if one is open.
+func (m *Markdown) EndFile(file io.Writer) {
+	if m.fence == "" {
+		return
+	}
+	if !m.newline {
+		writeString(file, "\n")
+	}
+	writeString(file, m.fence+"\n\n")
+	m.fence = ""
+}
+
+// Tree writes the listing as a text code block.
+func (m *Markdown) Tree(file io.Writer, root *TreeNode) {
+	var listing bytes.Buffer
+	WriteTree(&listing, root)
+	fence := fenceFor(listing.Bytes())
+	writeString(file, fence+"text\n"+listing.String()+fence+"\n")
+}
+
+// fenceFor returns a backtick fence longer than any run of backticks in content, and at least three long.
+func fenceFor(content []byte) string {
+	var scan contentScan
+	_, _ = scan.Write(content)
+	return scan.fence()
+}
+
+// headLen is how much of the start of a content contentScan keeps, enough for a shebang line.
+const headLen = 512
+
+// contentScan is an io.Writer taking note of what fencing a content needs, however long it is.
+type contentScan struct {
+	head    []byte // head is the start of the content, for its shebang line.
+	size    int64
+	last    byte // last is the last byte of the content.
+	longest int  // longest is the longest run of backticks.
+	run     int  // run is the run of backticks the content ends with.
+}
+
+func (s *contentScan) Write(p []byte) (int, error) {
+	if len(s.head) < headLen {
+		s.head = append(s.head, p[:min(len(p), headLen-len(s.head))]...)
+	}
+	for _, b := range p {
+		if b != '`' {
+			s.run = 0
+			continue
+		}
+		s.run++
+		s.longest = max(s.longest, s.run)
+	}
+	if len(p) > 0 {
+		s.size += int64(len(p))
+		s.last = p[len(p)-1]
+	}
+	return len(p), nil
+}
+
+// fence returns a backtick fence longer than any run of backticks seen, and at least three long.
+func (s *contentScan) fence() string {
+	return strings.Repeat("`", max(3, s.longest+1))
+}
+
+// languages maps file extensions to the language names of Markdown code blocks.
+var languages = map[string]string{
+	".go": "go",
+	".js": "javascript", ".mjs": "javascript", ".cjs": "javascript", ".jsx": "jsx", ".ts": "typescript", ".tsx": "tsx",
+	".vue": "vue", ".svelte": "svelte", ".html": "html", ".htm": "html", ".css": "css", ".scss": "scss", ".less": "less",
+	".py": "python", ".pyi": "python", ".rb": "ruby", ".php": "php", ".pl": "perl", ".lua": "lua", ".r": "r",
+	".rs": "rust", ".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".java": "java", ".kt": "kotlin", ".kts": "kotlin", ".scala": "scala", ".groovy": "groovy", ".gradle": "groovy",
+	".swift": "swift", ".dart": "dart", ".ex": "elixir", ".exs": "elixir", ".erl": "erlang",
+	".hs": "haskell", ".clj": "clojure", ".zig": "zig", ".nim": "nim",
+	".sh": "bash", ".bash": "bash", ".zsh": "zsh", ".fish": "fish", ".ps1": "powershell", ".bat": "batch",
+	".sql": "sql", ".graphql": "graphql", ".proto": "protobuf", ".tf": "hcl", ".hcl": "hcl",
+	".json": "json", ".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".ini": "ini", ".xml": "xml", ".csv": "csv",
+	".md": "markdown", ".rst": "rst", ".tex": "latex", ".diff": "diff", ".patch": "diff",
+}
+
+// fileLanguages maps file names without a telling extension to their language.
+var fileLanguages = map[string]string{
+	"Dockerfile": "dockerfile", "Makefile": "makefile", "GNUmakefile": "makefile", "CMakeLists.txt": "cmake",
+	"Gemfile": "ruby", "Rakefile": "ruby", "Jenkinsfile": "groovy", "Vagrantfile": "ruby",
+}
+
+// interpreters maps the interpreters of shebang lines to their language.
+var interpreters = map[string]string{
+	"sh": "sh", "bash": "bash", "zsh": "zsh", "fish": "fish", "python": "python", "node": "javascript",
+	"deno": "typescript", "ruby": "ruby", "perl": "perl", "php": "php", "lua": "lua", "Rscript": "r",
+}
+
+// Language returns the language name of a Markdown code block for the file filePath, chosen by its name or
+// extension, or else by the shebang line of content. It is empty when neither tells.
+func Language(filePath string, content []byte) string {
+	name := path.Base(filePath)
+	if language, found := fileLanguages[name]; found {
+		return language
+	}
+	if language, found := languages[strings.ToLower(path.Ext(name))]; found {
+		return language
+	}
+	return shebangLanguage(content)
+}
+
+// shebangLanguage returns the language of the interpreter named by a "#!/usr/bin/env python3" line.
+func shebangLanguage(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's own options, such as -S
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return ""
+		}
+		interpreter = fields[0]
+	}
+	// python3, python3.12 and ruby2.7 are python and ruby
+	return interpreters[strings.TrimRight(interpreter, "0123456789.")]
+}
@@ -0,0 +1,91 @@
+// Package output. tree writes a directory listing in the style of tree(1), with file sizes.
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/seyedali-dev/treeclip/pkg/utils"
+)
+
+// TreeNode is a file or directory of a listing written by WriteTree.
+type TreeNode struct {
+	Name     string
+	Dir      bool
+	Size     int64  // Size is written for files without a Note.
+	Target   string // Target is set for symlinks, written as "name -> target".
+	Note     string // Note replaces the size, e.g. "named pipe" or an error.
+	Children []*TreeNode
+}
+
+// Counts returns the number of directories and files below n, n itself excluded.
+func (n *TreeNode) Counts() (dirs, files int) {
+	for _, child := range n.Children {
+		if child.Dir {
+			dirs++
+		} else {
+			files++
+		}
+		childDirs, childFiles := child.Counts()
+		dirs, files = dirs+childDirs, files+childFiles
+	}
+	return dirs, files
+}
+
+// WriteTree writes root and everything below it, one entry per line:
+//
+//	project
+//	├── [1.2 KB]  go.mod
+//	├── cmd
+//	│   └── [4.5 KB]  run.go
+//	└── docs -> ../docs
+//
+//	2 directories, 2 files
+func WriteTree(file io.Writer, root *TreeNode) {
+	writeTreeLine(file, root.Name)
+	writeTreeChildren(file, root, "")
+
+	dirs, files := root.Counts()
+	writeTreeLine(file, fmt.Sprintf("\n%d %s, %d %s", dirs, plural(dirs, "directory", "directories"), files, plural(files, "file", "files")))
+}
+
+// writeTreeChildren writes the children of node, each line starting with prefix.
+func writeTreeChildren(file io.Writer, node *TreeNode, prefix string) {
+	for i, child := range node.Children {
+		branch, indent := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		writeTreeLine(file, prefix+branch+treeEntry(child))
+		writeTreeChildren(file, child, prefix+indent)
+	}
+}
+
+// treeEntry formats a node without its branch: "[size]  name", "[note]  name" or "name -> target".
+func treeEntry(node *TreeNode) string {
+	entry := node.Name
+	if node.Target != "" {
+		entry += " -> " + node.Target
+	}
+	switch {
+	case node.Note != "":
+		return fmt.Sprintf("[%s]  %s", node.Note, entry)
+	case node.Dir || node.Target != "":
+		return entry
+	default:
+		return fmt.Sprintf("[%s]  %s", utils.FormatBytes(node.Size), entry)
+	}
+}
+
+func writeTreeLine(file io.Writer, line string) {
+	if _, err := fmt.Fprintln(file, line); err != nil {
+		panic(fmt.Sprintf("❌🪲  [ERROR] failed to write to file: %v", err))
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
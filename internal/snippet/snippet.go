@@ -0,0 +1,94 @@
+// Package snippet selects parts of files by line numbers, such as "120-240", or by the name of a Go declaration.
+package snippet
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is a span of 1-based lines, End included. An End of 0 means up to the end of the file.
+type Range struct {
+	Start  int
+	End    int
+	Symbol string // Symbol is the declaration the range was resolved from, if any.
+}
+
+// ParseRange parses a line range: "120-240", "120" for a single line or "120-" for everything from line 120 on.
+func ParseRange(spec string) (Range, error) {
+	startSpec, endSpec, isSpan := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(startSpec)
+	if err != nil || start < 1 {
+		return Range{}, fmt.Errorf("invalid line range %q, expected e.g. 120-240, 120 or 120- (ノಠ益ಠ)ノ", spec)
+	}
+	r := Range{Start: start, End: start}
+	if isSpan {
+		r.End = 0
+		if endSpec != "" {
+			if r.End, err = strconv.Atoi(endSpec); err != nil || r.End < start {
+				return Range{}, fmt.Errorf("invalid line range %q, expected e.g. 120-240, 120 or 120- (ノಠ益ಠ)ノ", spec)
+			}
+		}
+	}
+	return r, nil
+}
+
+// String formats the range as "120-240", "120" or "120-", followed by " #Symbol" when it has one.
+func (r Range) String() string {
+	var s string
+	switch r.End {
+	case r.Start:
+		s = strconv.Itoa(r.Start)
+	case 0:
+		s = strconv.Itoa(r.Start) + "-"
+	default:
+		s = fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+	if r.Symbol != "" {
+		s += " #" + r.Symbol
+	}
+	return s
+}
+
+// Cut returns the lines of data within r and the range they actually cover: End is clamped to the last line.
+// ok is false when r starts past the last line.
+func Cut(data []byte, r Range) (part []byte, span Range, ok bool) {
+	span = r
+	line, offset := 1, 0
+	for line < r.Start {
+		next := bytes.IndexByte(data[offset:], '\n')
+		if next < 0 || offset+next+1 == len(data) {
+			return nil, span, false
+		}
+		offset += next + 1
+		line++
+	}
+
+	start := offset
+	for r.End == 0 || line <= r.End {
+		next := bytes.IndexByte(data[offset:], '\n')
+		if next < 0 {
+			offset = len(data)
+			break
+		}
+		offset += next + 1
+		if offset == len(data) {
+			break
+		}
+		line++
+	}
+	if r.End == 0 || line < r.End {
+		span.End = line
+	}
+	return data[start:offset], span, true
+}
+
+// LineCount returns the number of lines of data, a last line without a newline included.
+func LineCount(data []byte) int {
+	count := bytes.Count(data, []byte{'\n'})
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,119 @@
+// Package snippet. symbol resolves the name of a Go declaration to its lines.
+package snippet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// GoSymbol returns the lines of the top-level declaration symbol in the Go source src, its doc comment included.
+// symbol names a function, type, variable or constant, or a method as "Type.Method". A bare method name is
+// accepted too when no other declaration has it and a single type has such a method.
+func GoSymbol(filename string, src []byte, symbol string) (Range, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return Range{}, fmt.Errorf("failed to parse %s to find #%s: %w (ノಠ益ಠ)ノ", filename, symbol, err)
+	}
+
+	var found, methods []ast.Node
+	var methodNames []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				if decl.Name.Name == symbol {
+					found = append(found, decl)
+				}
+				continue
+			}
+			name := receiverType(decl.Recv.List[0].Type) + "." + decl.Name.Name
+			switch symbol {
+			case name:
+				found = append(found, decl)
+			case decl.Name.Name:
+				methods = append(methods, decl)
+				methodNames = append(methodNames, name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if !declares(spec, symbol) {
+					continue
+				}
+				// A lone spec takes its keyword and doc comment along, one of a group only itself
+				if len(decl.Specs) == 1 {
+					found = append(found, decl)
+				} else {
+					found = append(found, spec)
+				}
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		if len(methods) > 1 {
+			return Range{}, fmt.Errorf("#%s is ambiguous in %s, name one of %s (ノಠ益ಠ)ノ", symbol, filename, strings.Join(methodNames, ", "))
+		}
+		found = methods
+	}
+	if len(found) == 0 {
+		return Range{}, fmt.Errorf("no declaration named %s in %s (ノಠ益ಠ)ノ", symbol, filename)
+	}
+
+	node := found[0]
+	start := node.Pos()
+	if doc := docOf(node); doc != nil {
+		start = doc.Pos()
+	}
+	return Range{Start: fset.Position(start).Line, End: fset.Position(node.End()).Line, Symbol: symbol}, nil
+}
+
+// receiverType returns the name of a method's receiver type, without its pointer or type parameters.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// declares reports whether the type, variable or constant spec declares name.
+func declares(spec ast.Spec, name string) bool {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Name.Name == name
+	case *ast.ValueSpec:
+		return slices.ContainsFunc(spec.Names, func(ident *ast.Ident) bool { return ident.Name == name })
+	}
+	return false
+}
+
+// docOf returns the doc comment of a declaration, nil if it has none.
+func docOf(node ast.Node) *ast.CommentGroup {
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		return node.Doc
+	case *ast.GenDecl:
+		return node.Doc
+	case *ast.TypeSpec:
+		return node.Doc
+	case *ast.ValueSpec:
+		return node.Doc
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Package traversal. collect walks the tree and picks the files to write, following symlinks on request.
+package traversal
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/seyedali-dev/treeclip/internal/snippet"
+)
+
+// walkedFile is a file the walk selected for writing.
+type walkedFile struct {
+	rel      string
+	info     fs.FileInfo
+	tooLarge bool            // tooLarge files exceed Options.MaxFileSize, only a placeholder is written and they are never read.
+	special  string          // special is the kind of a FIFO, socket or device, which is never opened, see specialKind.
+	lines    []snippet.Range // lines are the parts of the file to write, the whole file when empty, see Options.Lines.
+
+	link     bool   // link is set for symlinks that are listed instead of followed.
+	target   string // target is the link's target as written in the link.
+	linkNote string // linkNote explains why a link was not followed despite Options.FollowSymlinks.
+
+	failure error // failure is set for entries that could not be read, when Options.OnError doesn't abort.
+	root    int   // root is the index of the first of Options.Roots holding the file, -1 without roots.
+
+	sniffOnly bool // sniffOnly files are only read as far as the head, for listings that don't need the content.
+}
+
+// size returns the size of the file, 0 when it could not be read.
+func (f walkedFile) size() int64 {
+	if f.info == nil {
+		return 0
+	}
+	return f.info.Size()
+}
+
+// modTime returns the modification time of the file, the zero time when it could not be read.
+func (f walkedFile) modTime() time.Time {
+	if f.info == nil {
+		return time.Time{}
+	}
+	return f.info.ModTime()
+}
+
+// collector holds the state of a single walk.
+type collector struct {
+	tree  Tree
+	opts  Options
+	rules *ruleSet
+	roots rootSet
+	stats *Stats
+	files []walkedFile
+	dirs  []string // dirs are the directories the walk kept, including those past Options.MaxDepth, in walk order.
+}
+
+// collectFiles walks the tree and returns the files to write in walk order. Everything decided by paths,
+// attributes and file info is counted in stats here; decisions that need the content are left to the writer.
+//
+// Directories are read in lexical order like fs.WalkDir. Symlinks are listed as links unless
+// Options.FollowSymlinks is set, in which case they are walked like the file or directory they point to.
+// A link to one of its own parent directories is a cycle and is listed instead of followed.
+func collectFiles(tree Tree, opts Options, stats *Stats) ([]walkedFile, error) {
+	c, err := collect(tree, opts, stats)
+	if err != nil {
+		return nil, err
+	}
+	return c.files, nil
+}
+
+// collect walks the tree like collectFiles and returns the finished collector, with the kept directories too.
+func collect(tree Tree, opts Options, stats *Stats) (*collector, error) {
+	c := &collector{tree: tree, opts: opts, rules: newRuleSet(tree, opts), roots: cleanRoots(opts.Roots), stats: stats}
+
+	var ancestors []fileID
+	if opts.FollowSymlinks {
+		rootInfo, err := fs.Stat(tree.FS, ".")
+		if err != nil {
+			return nil, err
+		}
+		if id, ok := fileIDOf(rootInfo); ok {
+			ancestors = append(ancestors, id)
+		}
+	}
+	if opts.Files != nil {
+		if err := c.visitListed(); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	if err := c.enterDir(".", ancestors); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// enterDir loads the ignore and attribute files of the directory rel and visits its entries.
+// ancestors are the identities of rel and its parent directories, used to detect symlink cycles.
+func (c *collector) enterDir(rel string, ancestors []fileID) error {
+	// Read the directory first, an unreadable one has no readable ignore files either
+	entries, err := fs.ReadDir(c.tree.FS, rel)
+	if err != nil {
+		err = &fileError{action: "reading directory", name: c.tree.Name(rel), err: err}
+		if rel == "." {
+			return err
+		}
+		return c.fail(rel, err)
+	}
+
+	if err := c.loadDirRules(rel); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := c.visit(path.Join(rel, entry.Name()), entry, ancestors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDirRules loads the ignore and attribute files of the directory rel.
+func (c *collector) loadDirRules(rel string) error {
+	if err := c.rules.loadIgnoreFiles(rel); err != nil {
+		return err
+	}
+	return c.rules.loadAttributeFiles(rel)
+}
+
+// visit handles a single entry of a directory.
+func (c *collector) visit(rel string, d fs.DirEntry, ancestors []fileID) error {
+	file := walkedFile{rel: rel, root: -1}
+	isDir := d.IsDir()
+	if d.Type()&fs.ModeSymlink != 0 {
+		var err error
+		if file, err = c.resolveLink(file, d, ancestors); err != nil {
+			return c.fail(rel, err)
+		}
+		isDir = !file.link && file.info.IsDir()
+	}
+
+	if c.opts.Only != nil && !c.opts.Only.contains(rel, isDir) {
+		return nil
+	}
+	// The roots themselves and the directories leading to them are never excluded, like the root of the tree
+	explicit := false
+	if len(c.roots) > 0 {
+		file.root = c.roots.index(rel)
+		switch {
+		case isDir && c.roots.leadsTo(rel):
+			explicit = true
+		case file.root < 0:
+			return nil
+		default:
+			explicit = c.roots[file.root] == rel
+		}
+	}
+	if !explicit && c.rules.match(rel, isDir).Excluded {
+		c.stats.Skipped++
+		return nil
+	}
+	if isDir {
+		c.dirs = append(c.dirs, rel)
+		if c.opts.MaxDepth > 0 && depth(rel) >= c.opts.MaxDepth {
+			c.stats.TooDeep++
+			return nil
+		}
+		if c.opts.FollowSymlinks {
+			if err := c.loadInfo(&file, d); err != nil {
+				return c.fail(rel, err)
+			}
+			if id, ok := fileIDOf(file.info); ok {
+				ancestors = append(slices.Clip(ancestors), id)
+			}
+		}
+		return c.enterDir(rel, ancestors)
+	}
+	if file.link {
+		c.files = append(c.files, file)
+		return nil
+	}
+
+	if c.rules.generated(rel) {
+		c.stats.Generated++
+		return nil
+	}
+	if err := c.loadInfo(&file, d); err != nil {
+		return c.fail(rel, err)
+	}
+	if file.special = specialKind(file.info.Mode()); file.special != "" {
+		c.files = append(c.files, file)
+		return nil
+	}
+	if file.info.ModTime().Before(c.opts.ModifiedSince) {
+		c.stats.Older++
+		return nil
+	}
+	// Only the selected lines of a file count against MaxFileSize, checked once they are cut
+	file.lines = c.opts.Lines[rel]
+	file.tooLarge = c.opts.MaxFileSize > 0 && file.info.Size() > c.opts.MaxFileSize && file.lines == nil
+	c.files = append(c.files, file)
+	return nil
+}
+
+// fail handles an entry that could not be read: it aborts the walk with err, or with Options.OnError set to skip
+// or warn, keeps the entry so the writer can report it.
+func (c *collector) fail(rel string, err error) error {
+	if c.opts.OnError == "" || c.opts.OnError == OnErrorAbort {
+		return err
+	}
+	c.files = append(c.files, walkedFile{rel: rel, failure: err, root: c.roots.index(rel)})
+	return nil
+}
+
+// loadInfo sets the info of file from its directory entry, unless it is already known.
+func (c *collector) loadInfo(file *walkedFile, d fs.DirEntry) error {
+	if file.info != nil {
+		return nil
+	}
+	info, err := d.Info()
+	if err != nil {
+		return &fileError{action: "reading file info", name: c.tree.Name(file.rel), err: err}
+	}
+	file.info = info
+	return nil
+}
+
+// resolveLink decides whether the symlink d is followed. A followed link gets the info of its target;
+// otherwise it is marked as a link, with a note when following was asked for but is not possible.
+func (c *collector) resolveLink(file walkedFile, d fs.DirEntry, ancestors []fileID) (walkedFile, error) {
+	if err := c.loadInfo(&file, d); err != nil {
+		return file, err
+	}
+	file.link = true
+	if linkFS, ok := c.tree.FS.(ReadLinkFS); ok {
+		target, err := linkFS.ReadLink(file.rel)
+		if err != nil {
+			return file, &fileError{action: "reading symlink", name: c.tree.Name(file.rel), err: err}
+		}
+		file.target = target
+	}
+	if !c.opts.FollowSymlinks {
+		return file, nil
+	}
+
+	targetInfo, err := fs.Stat(c.tree.FS, file.rel)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		file.linkNote = "broken link"
+		return file, nil
+	case err != nil:
+		return file, &fileError{action: "following symlink", name: c.tree.Name(file.rel), err: err}
+	case targetInfo.Mode()&fs.ModeSymlink != 0:
+		return file, nil // the tree can't resolve links, e.g. a git revision
+	}
+
+	if targetInfo.IsDir() {
+		id, ok := fileIDOf(targetInfo)
+		if !ok {
+			file.linkNote = "can't detect cycles here"
+			return file, nil
+		}
+		if slices.Contains(ancestors, id) {
+			file.linkNote = "cycle, not followed"
+			return file, nil
+		}
+	}
+	file.link, file.info = false, targetInfo
+	return file, nil
+}
+
+// depth is the number of segments of the slash-separated rel, 1 for the entries of the root.
+func depth(rel string) int {
+	return strings.Count(rel, "/") + 1
+}
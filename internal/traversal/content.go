@@ -0,0 +1,98 @@
+// Package traversal. content decides whether a file's content is written or replaced by a placeholder.
+package traversal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+)
+
+// DefaultGeneratedMarkers are the regular expressions that mark generated code when found in a file's first lines:
+// Go's "// Code generated ... DO NOT EDIT." convention and the "@generated" tag used by many other generators.
+var DefaultGeneratedMarkers = []string{
+	`^// Code generated .* DO NOT EDIT\.$`,
+	`@generated`,
+}
+
+// DefaultGeneratedLines is how many leading lines are searched for generated code markers by default.
+const DefaultGeneratedLines = 20
+
+// sniffLen is how many leading bytes of a file are inspected to tell text from binary content, as many as git reads.
+const sniffLen = 8000
+
+// Placeholder reasons written instead of the content of skipped files.
+const (
+	reasonBinary    = "binary"
+	reasonTooLarge  = "over --max-file-size"
+	reasonOverTotal = "over --max-total-size"
+)
+
+// specialKind names the kind of a file that must not be opened, such as a named pipe whose open blocks until a
+// writer shows up. It returns "" for regular files and directories.
+func specialKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	case mode&fs.ModeIrregular != 0:
+		return "irregular file"
+	}
+	return ""
+}
+
+// readHead reads up to sniffLen bytes from r. A file shorter than that is not an error.
+func readHead(r io.Reader) ([]byte, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return head[:n], err
+}
+
+// isBinary reports whether head looks like binary content: it holds a NUL byte, as git checks, or more than
+// one byte in twenty is a control character that text doesn't use. Bytes above 0x7f never count, so Latin-1 and
+// Windows-1252 text is kept like UTF-8.
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	controls := 0
+	for _, b := range head {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != '\b' && b != 0x1b || b == 0x7f {
+			controls++
+		}
+	}
+	return controls*20 > len(head)
+}
+
+// isGenerated reports whether one of the first lines of head matches one of markers.
+// Only the sniffed head is searched, so lines past sniffLen bytes are never read.
+func isGenerated(head []byte, markers []*regexp.Regexp, lines int) bool {
+	for i := 0; i < lines && len(head) > 0; i++ {
+		line, rest, _ := bytes.Cut(head, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		for _, marker := range markers {
+			if marker.Match(line) {
+				return true
+			}
+		}
+		head = rest
+	}
+	return false
+}
+
+// closeFile closes a file of the traversed tree, panicking on error like utils.SafeCloseFile.
+func closeFile(file fs.File) {
+	if err := file.Close(); err != nil {
+		panic(fmt.Sprintf("⚠️  Warning! failed to close opened file: %v ╰（‵□′）╯", err))
+	}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package traversal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestTraverseDirSkipsFIFO checks that a named pipe is listed with a placeholder instead of blocking the walk.
+func TestTraverseDirSkipsFIFO(t *testing.T) {
+	root := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0o644); err != nil {
+		t.Skipf("can't create a FIFO here: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	var stats Stats
+	go func() {
+		var err error
+		stats, err = TraverseDir(root, Options{}, &out)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("TraverseDir: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TraverseDir did not return, the FIFO was opened")
+	}
+
+	want := "==> main.go\npackage main\n\n==> pipe\n[named pipe, skipped]\n\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if stats.Special != 1 || stats.Processed != 1 {
+		t.Fatalf("stats = %+v, want 1 special and 1 processed", stats)
+	}
+}
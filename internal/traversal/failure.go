@@ -0,0 +1,80 @@
+// Package traversal. failure decides what happens to files that can't be read, see Options.OnError.
+package traversal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// ErrorMode is what a traversal does when a file or directory can't be read.
+type ErrorMode string
+
+const (
+	OnErrorAbort ErrorMode = "abort" // OnErrorAbort stops at the first failure and returns it, the default.
+	OnErrorSkip  ErrorMode = "skip"  // OnErrorSkip writes a marker in place of the content and records the failure in Stats.
+	OnErrorWarn  ErrorMode = "warn"  // OnErrorWarn is OnErrorSkip plus a line on Options.Warnings as soon as it happens.
+)
+
+// ErrorModes lists every ErrorMode.
+var ErrorModes = []ErrorMode{OnErrorAbort, OnErrorSkip, OnErrorWarn}
+
+// ParseErrorMode parses an --on-error value. The empty string is OnErrorAbort.
+func ParseErrorMode(value string) (ErrorMode, error) {
+	if value == "" {
+		return OnErrorAbort, nil
+	}
+	mode := ErrorMode(strings.ToLower(value))
+	if !slices.Contains(ErrorModes, mode) {
+		return "", fmt.Errorf("unknown error mode %q, expected one of abort, skip, warn (ノಠ益ಠ)ノ", value)
+	}
+	return mode, nil
+}
+
+// Failure is a file or directory that could not be read.
+type Failure struct {
+	Path   string // Path is slash-separated and relative to the traversal root.
+	Reason string // Reason is short, e.g. "opening file: permission denied".
+}
+
+// String formats the failure as "path: reason".
+func (f Failure) String() string {
+	return f.Path + ": " + f.Reason
+}
+
+// fileError is a failure to read an entry of the tree. Its message is the one a traversal aborts with,
+// while reason is what is written into the output and the failure report.
+type fileError struct {
+	action string // action is what failed, e.g. "opening file".
+	name   string // name is the entry as displayed by Tree.Name.
+	err    error
+}
+
+func (e *fileError) Error() string {
+	return fmt.Sprintf("❌🪲  [ERROR] error %s %v: %v", e.action, e.name, e.err)
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
+}
+
+// reason describes the failure without the path, which the output already shows.
+func (e *fileError) reason() string {
+	cause := e.err
+	var pathErr *fs.PathError
+	if errors.As(cause, &pathErr) {
+		cause = pathErr.Err
+	}
+	return e.action + ": " + cause.Error()
+}
+
+// failureOf turns the error of a skipped entry into a Failure.
+func failureOf(rel string, err error) Failure {
+	var fileErr *fileError
+	if errors.As(err, &fileErr) {
+		return Failure{Path: rel, Reason: fileErr.reason()}
+	}
+	return Failure{Path: rel, Reason: err.Error()}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+// Package traversal. fileid_other is the fallback for systems without device and inode numbers.
+package traversal
+
+import "io/fs"
+
+// fileID identifies a file independently of the path it was reached through.
+type fileID struct{}
+
+// fileIDOf always fails, so symlinked directories are never followed where cycles can't be detected.
+func fileIDOf(fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
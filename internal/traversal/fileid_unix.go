@@ -0,0 +1,24 @@
+//go:build unix
+
+// Package traversal. fileid_unix identifies files by device and inode number.
+package traversal
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID identifies a file independently of the path it was reached through.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// fileIDOf returns the device and inode of info. ok is false for infos that don't come from the local filesystem.
+func fileIDOf(info fs.FileInfo) (id fileID, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
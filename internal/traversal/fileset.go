@@ -0,0 +1,40 @@
+// Package traversal. fileset limits a traversal to a known list of files.
+package traversal
+
+import (
+	"path"
+	"strings"
+)
+
+// FileSet is a set of slash-separated file paths relative to the traversal root, along with their parent directories
+// so the walk can skip every directory that holds none of them.
+type FileSet struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// NewFileSet builds a FileSet from paths relative to the traversal root.
+func NewFileSet(paths []string) *FileSet {
+	set := &FileSet{files: map[string]bool{}, dirs: map[string]bool{".": true}}
+	for _, p := range paths {
+		p = path.Clean(strings.TrimPrefix(p, "./"))
+		set.files[p] = true
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			set.dirs[dir] = true
+		}
+	}
+	return set
+}
+
+// Len returns the number of files in the set.
+func (s *FileSet) Len() int {
+	return len(s.files)
+}
+
+// contains reports whether rel is one of the files, or for directories, leads to one of them.
+func (s *FileSet) contains(rel string, isDir bool) bool {
+	if isDir {
+		return s.dirs[rel]
+	}
+	return s.files[rel]
+}
@@ -0,0 +1,156 @@
+// Package traversal. lint checks the .treeclipignore files of a tree against the paths they are meant to exclude.
+package traversal
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+)
+
+// lintEntry is a path of the linted tree.
+type lintEntry struct {
+	rel   string
+	isDir bool
+}
+
+// ignoreFile is an ignore file found in the linted tree.
+type ignoreFile struct {
+	name string // name is one of Options.IgnoreFiles.
+	dir  string // dir is the slash-separated directory of the file, relative to the root.
+	path string // path is the slash-separated path of the file inside the tree.
+}
+
+// LintTree checks every .treeclipignore file of tree, such as a directory, a git revision or an archive: the per-line checks of exclude.LintLines, plus patterns that
+// match nothing in the tree and patterns that change nothing because earlier ones already decided every path they match.
+// "Earlier" follows TraverseDir's precedence: opts.Defaults, opts.Patterns, the other IgnoreFiles, then the
+// .treeclipignore files themselves, parents before subdirectories and lines in order. Unlike the traversal,
+// LintTree walks excluded directories too (only .git is skipped), so it can tell which paths a pattern would
+// have matched.
+func LintTree(tree Tree, opts Options) ([]exclude.Issue, error) {
+	var entries []lintEntry
+	var files []ignoreFile
+	err := fs.WalkDir(tree.FS, ".", func(rel string, d fs.DirEntry, e error) error {
+		if e != nil {
+			return e
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if rel != "." {
+			entries = append(entries, lintEntry{rel: rel, isDir: d.IsDir()})
+		}
+		if d.IsDir() {
+			for _, name := range opts.IgnoreFiles {
+				files = append(files, ignoreFile{name: name, dir: rel, path: path.Join(rel, name)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prior := exclude.NewMatcher(opts.Defaults)
+	prior.Add(opts.Patterns...)
+	for _, file := range files {
+		if file.name == exclude.IgnoreFileName {
+			continue
+		}
+		patterns, err := exclude.LoadPatternFileFS(tree.FS, file.path)
+		if err != nil {
+			return nil, err
+		}
+		prior.Add(exclude.ScopePatterns(tree.withNames(patterns), file.dir)...)
+	}
+
+	var issues []exclude.Issue
+	for _, file := range files {
+		if file.name != exclude.IgnoreFileName {
+			continue
+		}
+		lineIssues, patterns, err := exclude.LintFileFS(tree.FS, file.path, opts.Defaults)
+		if err != nil {
+			return nil, err
+		}
+		for i := range lineIssues {
+			lineIssues[i].Source.Name = tree.Name(lineIssues[i].Source.Name)
+		}
+		issues = append(issues, lineIssues...)
+
+		for _, pattern := range exclude.ScopePatterns(tree.withNames(patterns), file.dir) {
+			if issue, found := lintAgainstTree(tree.Name("."), pattern, entries, prior); found && !hasIssue(lineIssues, pattern) {
+				issues = append(issues, issue)
+			}
+			prior.Add(pattern)
+		}
+	}
+
+	slices.SortStableFunc(issues, func(a, b exclude.Issue) int {
+		return cmp.Or(strings.Compare(a.Source.Name, b.Source.Name), cmp.Compare(a.Source.Line, b.Source.Line))
+	})
+	return issues, nil
+}
+
+// lintAgainstTree reports a pattern that matches no entry, or whose matches are all decided already by prior.
+func lintAgainstTree(root string, pattern exclude.Pattern, entries []lintEntry, prior *exclude.Matcher) (exclude.Issue, bool) {
+	issue := exclude.Issue{Source: pattern.Source, Pattern: pattern.Raw}
+
+	var matched []lintEntry
+	for _, entry := range entries {
+		if pattern.Match(entry.rel, entry.isDir) {
+			matched = append(matched, entry)
+		}
+	}
+	if len(matched) == 0 {
+		issue.Kind, issue.Message = exclude.IssueUnused, "no file or folder in the tree matches it"
+		return issue, true
+	}
+
+	var decided exclude.Match
+	for _, entry := range matched {
+		match := prior.Explain(entry.rel, entry.isDir)
+		// A negation can't re-include anything inside an excluded directory, so those paths are decided too
+		if match.Excluded == pattern.Negate && match.Rule != exclude.RuleParentDir {
+			return exclude.Issue{}, false
+		}
+		decided = match
+	}
+
+	issue.Kind = exclude.IssueShadowed
+	switch {
+	case decided.Rule == exclude.RuleParentDir:
+		issue.Message = fmt.Sprintf("everything it matches is inside %s, excluded by %q (%s)", decided.Path, decided.Pattern.Raw, relSource(root, decided.Pattern.Source))
+	case pattern.Negate:
+		issue.Message = "nothing it matches is excluded by an earlier pattern"
+	default:
+		issue.Message = fmt.Sprintf("everything it matches is already excluded, e.g. %s by %q (%s)", decided.Path, decided.Pattern.Raw, relSource(root, decided.Pattern.Source))
+	}
+	return issue, true
+}
+
+// relSource shortens the path of file sources inside root.
+func relSource(root string, source exclude.Source) exclude.Source {
+	if source.Kind != exclude.SourceFile {
+		return source
+	}
+	if rel, err := filepath.Rel(root, source.Name); err == nil && !strings.HasPrefix(rel, "..") {
+		source.Name = filepath.ToSlash(rel)
+	}
+	return source
+}
+
+// hasIssue reports whether a per-line check already flagged the pattern, to avoid piling up reports on one line.
+func hasIssue(issues []exclude.Issue, pattern exclude.Pattern) bool {
+	for _, issue := range issues {
+		if issue.Source == pattern.Source {
+			return true
+		}
+	}
+	return false
+}
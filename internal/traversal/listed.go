@@ -0,0 +1,106 @@
+// Package traversal. listed visits a given list of files instead of walking the tree, see Options.Files.
+package traversal
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// visitListed visits the files of Options.Files, in walk order, without walking the tree. A file is only kept
+// if the walk would have reached it: its parent directories go through the exclusions and Options.MaxDepth, and
+// their ignore and attribute files are read on the way. Listed directories are left out.
+func (c *collector) visitListed() error {
+	files := make([]string, 0, len(c.opts.Files))
+	for _, file := range c.opts.Files {
+		files = append(files, path.Clean(strings.TrimPrefix(file, "./")))
+	}
+	slices.SortFunc(files, func(a, b string) int { return comparePaths(a, b, false) })
+	files = slices.Compact(files)
+
+	if err := c.loadDirRules("."); err != nil {
+		return err
+	}
+	kept := map[string]bool{".": true}
+	entries := map[string]map[string]fs.DirEntry{}
+	for _, rel := range files {
+		if rel == "." {
+			continue
+		}
+		dir := path.Dir(rel)
+		keep, err := c.keepDir(dir, kept)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+
+		// The parent directory gives the entry as the walk would see it, a symlink included
+		dirEntries, read := entries[dir]
+		if !read {
+			list, err := fs.ReadDir(c.tree.FS, dir)
+			if err != nil {
+				entries[dir] = nil
+				if err := c.fail(dir, &fileError{action: "reading directory", name: c.tree.Name(dir), err: err}); err != nil {
+					return err
+				}
+				continue
+			}
+			dirEntries = make(map[string]fs.DirEntry, len(list))
+			for _, entry := range list {
+				dirEntries[entry.Name()] = entry
+			}
+			entries[dir] = dirEntries
+		} else if dirEntries == nil {
+			continue // unreadable, reported already
+		}
+
+		entry, found := dirEntries[path.Base(rel)]
+		if !found {
+			err := &fileError{action: "opening file", name: c.tree.Name(rel), err: fs.ErrNotExist}
+			if err := c.fail(rel, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		if err := c.visit(rel, entry, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepDir reports whether the walk would have entered the directory rel, deciding its parents first.
+// kept caches the decisions.
+func (c *collector) keepDir(rel string, kept map[string]bool) (bool, error) {
+	if keep, decided := kept[rel]; decided {
+		return keep, nil
+	}
+	keep, err := c.keepDir(path.Dir(rel), kept)
+	if err != nil || !keep {
+		kept[rel] = false
+		return false, err
+	}
+
+	switch {
+	case c.rules.match(rel, true).Excluded:
+		c.stats.Skipped++
+		keep = false
+	case c.opts.MaxDepth > 0 && depth(rel) >= c.opts.MaxDepth:
+		c.dirs = append(c.dirs, rel)
+		c.stats.TooDeep++
+		keep = false
+	default:
+		c.dirs = append(c.dirs, rel)
+		if err := c.loadDirRules(rel); err != nil {
+			return false, err
+		}
+	}
+	kept[rel] = keep
+	return keep, nil
+}
@@ -0,0 +1,111 @@
+// Package traversal. listing builds the directory structure a traversal would write, without the file contents.
+package traversal
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/internal/output"
+)
+
+// ListTree returns the directories and files TraverseTree(tree, opts, ...) would write, decided by the very same
+// exclusions, as a listing rooted at tree.Name("."). Entries are sorted by name in every directory, like tree(1).
+// Files are only opened when opts.GeneratedMarkers need their first lines. With prune, directories that end up
+// without any file are left out, like tree --prune; directories past opts.MaxDepth are always kept.
+func ListTree(tree Tree, opts Options, prune bool) (root *output.TreeNode, stats Stats, err error) {
+	c, err := collect(tree, opts, &stats)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	root = &output.TreeNode{Name: tree.Name("."), Dir: true}
+	nodes := map[string]*output.TreeNode{".": root}
+	entered := map[*output.TreeNode]bool{root: true}
+	for _, dir := range c.dirs {
+		node := &output.TreeNode{Name: path.Base(dir), Dir: true}
+		nodes[dir] = node
+		entered[node] = opts.MaxDepth <= 0 || depth(dir) < opts.MaxDepth
+		parent := nodes[path.Dir(dir)]
+		parent.Children = append(parent.Children, node)
+	}
+
+	list := func(file walkedFile, content fileContent) error {
+		node := &output.TreeNode{Name: path.Base(file.rel)}
+		switch failure := cmp.Or(file.failure, content.err); {
+		case failure != nil:
+			if opts.OnError == "" || opts.OnError == OnErrorAbort {
+				return failure
+			}
+			f := failureOf(file.rel, failure)
+			recordFailure(&stats, opts, f)
+			if dir, isDir := nodes[file.rel]; isDir {
+				dir.Note = "error " + f.Reason // an unreadable directory is listed already
+				return nil
+			}
+			node.Note = "error " + f.Reason
+		case content.generated:
+			stats.Generated++
+			return nil
+		case file.link:
+			stats.Symlinks++
+			node.Target, node.Note = file.target, file.linkNote
+		case file.special != "":
+			stats.Special++
+			node.Note = file.special
+		case content.timedOut:
+			stats.TimedOut++
+			node.Note = fmt.Sprintf("read timed out after %s", opts.ReadTimeout)
+		default:
+			stats.Processed++
+			node.Size = file.info.Size()
+		}
+		parent := nodes[path.Dir(file.rel)]
+		parent.Children = append(parent.Children, node)
+		return nil
+	}
+
+	// Only generated code markers need the content, and only its head
+	if len(opts.GeneratedMarkers) > 0 && opts.GeneratedLines > 0 {
+		for i := range c.files {
+			c.files[i].sniffOnly = true
+		}
+		err = readFiles(tree, c.files, opts, list)
+	} else {
+		for _, file := range c.files {
+			if err = list(file, fileContent{}); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, stats, err
+	}
+
+	sortTree(root)
+	if prune {
+		pruneTree(root, entered)
+	}
+	return root, stats, nil
+}
+
+// sortTree sorts the children of every directory by name.
+func sortTree(node *output.TreeNode) {
+	slices.SortFunc(node.Children, func(a, b *output.TreeNode) int { return strings.Compare(a.Name, b.Name) })
+	for _, child := range node.Children {
+		sortTree(child)
+	}
+}
+
+// pruneTree drops the entered directories below node that hold no file, and reports whether node holds any.
+func pruneTree(node *output.TreeNode, entered map[*output.TreeNode]bool) bool {
+	if !node.Dir || !entered[node] || node.Note != "" {
+		return true
+	}
+	node.Children = slices.DeleteFunc(node.Children, func(child *output.TreeNode) bool {
+		return !pruneTree(child, entered)
+	})
+	return len(node.Children) > 0
+}
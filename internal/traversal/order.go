@@ -0,0 +1,106 @@
+// Package traversal. order sorts the collected files before they are written.
+package traversal
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+)
+
+// SortOrder is the order files are written in.
+type SortOrder string
+
+const (
+	SortPath      SortOrder = "path"       // SortPath is the walk order: lexical, directory by directory.
+	SortSize      SortOrder = "size"       // SortSize writes the smallest files first.
+	SortMTime     SortOrder = "mtime"      // SortMTime writes the most recently modified files first.
+	SortExt       SortOrder = "ext"        // SortExt groups files by extension.
+	SortDirsFirst SortOrder = "dirs-first" // SortDirsFirst writes the subdirectories of every directory before its files.
+)
+
+// SortOrders lists every SortOrder.
+var SortOrders = []SortOrder{SortPath, SortSize, SortMTime, SortExt, SortDirsFirst}
+
+// ParseSortOrder parses a --sort value. The empty string is SortPath.
+func ParseSortOrder(value string) (SortOrder, error) {
+	if value == "" {
+		return SortPath, nil
+	}
+	order := SortOrder(strings.ToLower(value))
+	if !slices.Contains(SortOrders, order) {
+		return "", fmt.Errorf("unknown sort order %q, expected one of path, size, mtime, ext, dirs-first (ノಠ益ಠ)ノ", value)
+	}
+	return order, nil
+}
+
+// sortFiles groups files by the first of Options.Roots holding them, then orders them by Options.Priority,
+// then by Options.Sort, then by path.
+func sortFiles(files []walkedFile, opts Options) {
+	if len(opts.Roots) < 2 && len(opts.Priority) == 0 && (opts.Sort == "" || opts.Sort == SortPath) {
+		return // already in walk order
+	}
+
+	ranks := make(map[string]int, len(files))
+	for _, file := range files {
+		ranks[file.rel] = priorityRank(file.rel, opts.Priority)
+	}
+	slices.SortStableFunc(files, func(a, b walkedFile) int {
+		if c := cmp.Compare(a.root, b.root); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(ranks[a.rel], ranks[b.rel]); c != 0 {
+			return c
+		}
+		var c int
+		switch opts.Sort {
+		case SortSize:
+			c = cmp.Compare(a.size(), b.size())
+		case SortMTime:
+			c = b.modTime().Compare(a.modTime())
+		case SortExt:
+			c = strings.Compare(path.Ext(a.rel), path.Ext(b.rel))
+		case SortDirsFirst:
+			return comparePaths(a.rel, b.rel, true)
+		}
+		return cmp.Or(c, comparePaths(a.rel, b.rel, false))
+	})
+}
+
+// priorityRank returns the index of the first priority pattern matching the file rel or one of its parent
+// directories, or len(priority) when none does.
+func priorityRank(rel string, priority []exclude.Pattern) int {
+	for i, pattern := range priority {
+		if pattern.Match(rel, false) {
+			return i
+		}
+		for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+			if pattern.Match(dir, true) {
+				return i
+			}
+		}
+	}
+	return len(priority)
+}
+
+// comparePaths compares slash-separated paths segment by segment, which is the order of the walk.
+// With dirsFirst, an entry that is a directory at the first differing segment sorts before a file.
+func comparePaths(a, b string, dirsFirst bool) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		if aDir, bDir := i < len(as)-1, i < len(bs)-1; dirsFirst && aDir != bDir {
+			if aDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(as[i], bs[i])
+	}
+	return cmp.Compare(len(as), len(bs))
+}
@@ -0,0 +1,162 @@
+// Package traversal. reader reads the collected files with a bounded pool of workers and hands them over in order.
+package traversal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// readAheadSize is the largest file the workers read in full before it is written. The content of a larger file
+// is streamed into the output when it is written, so read-ahead holds at most Options.Jobs times this many bytes.
+const readAheadSize = 1 << 20
+
+// fileContent is what reading a walkedFile produced.
+type fileContent struct {
+	data      []byte        // data is the content of a file read in full, empty for binary and generated files.
+	body      io.ReadCloser // body streams the content of a file not read in full, from its sniffed head on.
+	binary    bool
+	generated bool
+	timedOut  bool
+	err       error
+}
+
+// close closes the streamed body, if any.
+func (c fileContent) close() {
+	if c.body != nil {
+		if err := c.body.Close(); err != nil {
+			panic(fmt.Sprintf("⚠️  Warning! failed to close opened file: %v ╰（‵□′）╯", err))
+		}
+	}
+}
+
+// readFiles reads files and calls write for each of them, in order, on the calling goroutine. write must close
+// the streamed body of the contents it gets.
+// With opts.Jobs > 1 up to that many files are read ahead in parallel; a file is only read once fewer than
+// opts.Jobs earlier files are waiting to be written. Since files over readAheadSize are only opened and sniffed
+// ahead, this bounds both the open files and the bytes held by read-ahead contents.
+// The first error returned by write stops the reading.
+func readFiles(tree Tree, files []walkedFile, opts Options, write func(walkedFile, fileContent) error) error {
+	if opts.Jobs < 2 {
+		for _, file := range files {
+			if err := write(file, readFileTimeout(tree, file, opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	results := make([]chan fileContent, len(files))
+	for i := range results {
+		results[i] = make(chan fileContent, 1)
+	}
+	slots := make(chan struct{}, opts.Jobs)
+	done := make(chan struct{})
+	started := make(chan int, 1)
+
+	// Dispatcher: start a reader for every file as soon as a slot frees up
+	go func() {
+		for i, file := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				started <- i
+				return
+			}
+			go func() {
+				results[i] <- readFileTimeout(tree, file, opts)
+			}()
+		}
+		started <- len(files)
+	}()
+
+	// Sequencer: write in walk order, freeing a slot after each file
+	for i, file := range files {
+		content := <-results[i]
+		<-slots
+		if err := write(file, content); err != nil {
+			// Close the files read ahead once their readers return
+			close(done)
+			go func() {
+				for _, result := range results[i+1 : <-started] {
+					(<-result).close()
+				}
+			}()
+			return err
+		}
+	}
+	return nil
+}
+
+// readFileTimeout is readFile bounded by opts.ReadTimeout. A read that takes longer is given up on; its goroutine
+// is left behind, since a read stuck in the filesystem can't be interrupted, and closes the file if it ever
+// returns. Only the opening and sniffing of a streamed file is bounded, not the copy of its body.
+func readFileTimeout(tree Tree, file walkedFile, opts Options) fileContent {
+	if opts.ReadTimeout <= 0 {
+		return readFile(tree, file, opts)
+	}
+
+	result := make(chan fileContent, 1)
+	go func() {
+		result <- readFile(tree, file, opts)
+	}()
+	timer := time.NewTimer(opts.ReadTimeout)
+	defer timer.Stop()
+	select {
+	case content := <-result:
+		return content
+	case <-timer.C:
+		go func() { (<-result).close() }()
+		return fileContent{timedOut: true}
+	}
+}
+
+// readFile reads the content of a walked file. Links, special files and files over the size limit are not read at all,
+// and binary, generated and sniffOnly files are only read as far as the sniffed head. Unless their lines are
+// selected, files read one at a time, over readAheadSize or too large to ever fit opts.MaxTotalSize are left open
+// after the head for the caller to stream.
+func readFile(tree Tree, file walkedFile, opts Options) fileContent {
+	if file.tooLarge || file.link || file.special != "" || file.failure != nil {
+		return fileContent{}
+	}
+
+	openedFile, err := tree.FS.Open(file.rel)
+	if err != nil {
+		return fileContent{err: &fileError{action: "opening file", name: tree.Name(file.rel), err: err}}
+	}
+	streamed := false
+	defer func() {
+		if !streamed {
+			closeFile(openedFile)
+		}
+	}()
+
+	head, err := readHead(openedFile)
+	if err != nil {
+		return fileContent{err: &fileError{action: "reading file", name: tree.Name(file.rel), err: err}}
+	}
+	if isBinary(head) {
+		return fileContent{binary: true}
+	}
+	if isGenerated(head, opts.GeneratedMarkers, opts.GeneratedLines) {
+		return fileContent{generated: true}
+	}
+	if file.sniffOnly {
+		return fileContent{}
+	}
+	size := file.info.Size()
+	if file.lines == nil && (opts.Jobs < 2 || size > readAheadSize || opts.MaxTotalSize > 0 && size > opts.MaxTotalSize) {
+		streamed = true
+		return fileContent{body: struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), openedFile), openedFile}}
+	}
+
+	rest, err := io.ReadAll(openedFile)
+	if err != nil {
+		return fileContent{err: &fileError{action: "reading file", name: tree.Name(file.rel), err: err}}
+	}
+	return fileContent{data: append(head, rest...)}
+}
@@ -0,0 +1,172 @@
+package traversal
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/seyedali-dev/treeclip/internal/output"
+)
+
+// TestTraverseTreeJobsSameOutput checks that reading files in parallel writes exactly what a sequential read writes.
+func TestTraverseTreeJobsSameOutput(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := range 200 {
+		// Sizes vary a lot so that workers finish out of order
+		content := strings.Repeat(fmt.Sprintf("line %d of file %d\n", i, i), 1+(i*37)%500)
+		fsys[fmt.Sprintf("dir%d/sub%d/file%03d.txt", i%7, i%3, i)] = &fstest.MapFile{Data: []byte(content)}
+	}
+	fsys["assets/logo.png"] = &fstest.MapFile{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00")}
+	fsys["big.log"] = &fstest.MapFile{Data: bytes.Repeat([]byte("log line\n"), 200_000)}
+
+	traverse := func(jobs int) (string, Stats) {
+		var out bytes.Buffer
+		stats, err := TraverseTree(FSTree(fsys, "/src"), Options{Jobs: jobs, MaxFileSize: 1 << 20}, &out)
+		if err != nil {
+			t.Fatalf("TraverseTree with %d jobs: %v", jobs, err)
+		}
+		return out.String(), stats
+	}
+
+	sequential, sequentialStats := traverse(1)
+	for range 5 {
+		parallel, parallelStats := traverse(8)
+		if parallel != sequential {
+			t.Fatalf("output with 8 jobs differs from the sequential output:\n%s\n---\n%s", parallel, sequential)
+		}
+		if parallelStats.Processed != sequentialStats.Processed || parallelStats.Binary != sequentialStats.Binary ||
+			parallelStats.TooLarge != sequentialStats.TooLarge {
+			t.Fatalf("stats with 8 jobs = %+v, sequential = %+v", parallelStats, sequentialStats)
+		}
+	}
+	if sequentialStats.Processed != 200 || sequentialStats.Binary != 1 || sequentialStats.TooLarge != 1 {
+		t.Fatalf("stats = %+v, want 200 processed, 1 binary and 1 too large", sequentialStats)
+	}
+}
+
+// blockingFS is a MapFS whose Open blocks for one file until release is closed, like a stuck network filesystem.
+type blockingFS struct {
+	fstest.MapFS
+	stuck   string
+	release chan struct{}
+}
+
+func (b blockingFS) Open(name string) (fs.File, error) {
+	if name == b.stuck {
+		<-b.release
+	}
+	return b.MapFS.Open(name)
+}
+
+// TestTraverseTreeReadTimeout checks that a file whose open never returns is given up on after ReadTimeout.
+func TestTraverseTreeReadTimeout(t *testing.T) {
+	fsys := blockingFS{
+		MapFS: fstest.MapFS{
+			"a.txt":     {Data: []byte("a\n")},
+			"stuck.txt": {Data: []byte("never read\n")},
+			"z.txt":     {Data: []byte("z\n")},
+		},
+		stuck:   "stuck.txt",
+		release: make(chan struct{}),
+	}
+	t.Cleanup(func() { close(fsys.release) })
+
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	var stats Stats
+	go func() {
+		var err error
+		stats, err = TraverseTree(FSTree(fsys, "/src"), Options{Jobs: 2, ReadTimeout: 50 * time.Millisecond}, &out)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("TraverseTree: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TraverseTree did not return, the read timeout was not applied")
+	}
+
+	want := "==> a.txt\na\n\n==> stuck.txt\n[read timed out after 50ms, skipped]\n\n==> z.txt\nz\n\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if stats.TimedOut != 1 || stats.Processed != 2 {
+		t.Fatalf("stats = %+v, want 1 timed out and 2 processed", stats)
+	}
+}
+
+// TestTraverseTreeStreamsMarkdown checks that a content too large to read ahead is fenced from all of its
+// backticks and closed on a line of its own, whether it is streamed by the only job or by the sequencer.
+func TestTraverseTreeStreamsMarkdown(t *testing.T) {
+	content := strings.Repeat("some text\n", readAheadSize/10) + "``````` deep in the file\nno final newline"
+	fsys := fstest.MapFS{"big.md": {Data: []byte(content)}}
+	want := "### big.md\n\n````````markdown\n" + content + "\n````````\n\n"
+
+	for _, jobs := range []int{1, 8} {
+		var out bytes.Buffer
+		opts := Options{Jobs: jobs, Formatter: &output.Markdown{}}
+		if _, err := TraverseTree(FSTree(fsys, "/src"), opts, &out); err != nil {
+			t.Fatalf("TraverseTree with %d jobs: %v", jobs, err)
+		}
+		if out.String() != want {
+			t.Errorf("output with %d jobs = %.200q..., want %.200q...", jobs, out.String(), want)
+		}
+	}
+}
+
+// countingFS is a MapFS counting the bytes read from its files.
+type countingFS struct {
+	fstest.MapFS
+	read *atomic.Int64
+}
+
+func (c countingFS) Open(name string) (fs.File, error) {
+	file, err := c.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingFile{File: file, read: c.read}, nil
+}
+
+type countingFile struct {
+	fs.File
+	read *atomic.Int64
+}
+
+func (c countingFile) Read(p []byte) (int, error) {
+	n, err := c.File.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// TestTraverseTreeSkipsBodyOverTotal checks that a file whose size can't fit MaxTotalSize is only sniffed.
+func TestTraverseTreeSkipsBodyOverTotal(t *testing.T) {
+	for _, jobs := range []int{1, 8} {
+		fsys := countingFS{
+			MapFS: fstest.MapFS{"big.log": {Data: bytes.Repeat([]byte("log line\n"), 1<<20)}},
+			read:  &atomic.Int64{},
+		}
+		var out bytes.Buffer
+		stats, err := TraverseTree(FSTree(fsys, "/src"), Options{Jobs: jobs, MaxTotalSize: 1 << 20}, &out)
+		if err != nil {
+			t.Fatalf("TraverseTree with %d jobs: %v", jobs, err)
+		}
+		if want := "==> big.log\n[over --max-total-size, 9.0 MB, skipped]\n\n"; out.String() != want {
+			t.Errorf("output with %d jobs = %q, want %q", jobs, out.String(), want)
+		}
+		if stats.TooLarge != 1 {
+			t.Errorf("stats with %d jobs = %+v, want 1 too large", jobs, stats)
+		}
+		if read := fsys.read.Load(); read > sniffLen {
+			t.Errorf("%d bytes read with %d jobs, want only the sniffed head", read, jobs)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package traversal. roots limits a traversal to several files and directories below a common root.
+package traversal
+
+import (
+	"path"
+	"strings"
+)
+
+// rootSet is Options.Roots.
+type rootSet []string
+
+// index returns the index of the first root that is rel or holds it, -1 if none does.
+func (r rootSet) index(rel string) int {
+	for i, root := range r {
+		if root == rel || isBelow(rel, root) {
+			return i
+		}
+	}
+	return -1
+}
+
+// leadsTo reports whether the directory rel is one of the roots or one of their parent directories.
+func (r rootSet) leadsTo(rel string) bool {
+	for _, root := range r {
+		if root == rel || isBelow(root, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// label names the root of file in the output, with a trailing slash for directories.
+func (r rootSet) label(file walkedFile) string {
+	root := r[file.root]
+	if root == file.rel {
+		return root
+	}
+	return root + "/"
+}
+
+// isBelow reports whether the slash-separated rel is inside the directory dir.
+func isBelow(rel, dir string) bool {
+	return dir == "." && rel != "." || strings.HasPrefix(rel, dir+"/")
+}
+
+// cleanRoots cleans the slash-separated roots of a traversal.
+func cleanRoots(roots []string) rootSet {
+	cleaned := make(rootSet, len(roots))
+	for i, root := range roots {
+		cleaned[i] = path.Clean(strings.TrimPrefix(root, "./"))
+	}
+	return cleaned
+}
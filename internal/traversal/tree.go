@@ -0,0 +1,65 @@
+// Package traversal. tree abstracts the file tree a traversal reads, a directory on disk or any other fs.FS.
+package traversal
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+)
+
+// Tree is a file tree to traverse. Paths inside FS are slash-separated and relative to the tree's root.
+type Tree struct {
+	FS fs.FS
+	// Name returns how the path p inside FS is shown in errors and pattern sources, e.g. as an absolute file path.
+	Name func(p string) string
+}
+
+// ReadLinkFS is implemented by trees that can read the target of their symlinks.
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// DirTree is the Tree of the directory root on disk.
+func DirTree(root string) Tree {
+	return FSTree(dirFS{fsys: os.DirFS(root), root: root}, root)
+}
+
+// FSTree is the Tree of any fs.FS, such as an archive, an embed.FS or an fstest.MapFS. Paths are shown joined
+// to name, e.g. the archive's file path. Symlinks are only listed with their target if fsys implements ReadLinkFS.
+func FSTree(fsys fs.FS, name string) Tree {
+	return Tree{
+		FS: fsys,
+		Name: func(p string) string {
+			return filepath.Join(name, filepath.FromSlash(p))
+		},
+	}
+}
+
+// dirFS is os.DirFS plus ReadLink.
+type dirFS struct {
+	fsys fs.FS
+	root string
+}
+
+func (d dirFS) Open(name string) (fs.File, error)          { return d.fsys.Open(name) }
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) { return fs.ReadDir(d.fsys, name) }
+func (d dirFS) Stat(name string) (fs.FileInfo, error)      { return fs.Stat(d.fsys, name) }
+
+// ReadLink implements ReadLinkFS.
+func (d dirFS) ReadLink(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Readlink(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// withNames sets the Source of patterns read from the tree to their displayed file path.
+func (t Tree) withNames(patterns []exclude.Pattern) []exclude.Pattern {
+	for i := range patterns {
+		patterns[i].Source.Name = t.Name(patterns[i].Source.Name)
+	}
+	return patterns
+}
@@ -2,48 +2,458 @@
 package traversal
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/seyedali-dev/treeclip/internal/exclude"
 	"github.com/seyedali-dev/treeclip/internal/output"
-	"github.com/seyedali-dev/treeclip/pkg/utils"
+	"github.com/seyedali-dev/treeclip/internal/snippet"
 )
 
+// Options configures which entries TraverseDir writes.
+//
+// Exclusion patterns are evaluated with last-match-wins in this order: Defaults, Patterns, then the patterns of
+// every IgnoreFiles name (in the given order, each one covering all directories), then Overrides.
+// When Includes is not empty only the files it allows are written; excluded directories are never entered.
+type Options struct {
+	Defaults    []exclude.Pattern // Defaults are the built-in exclusions (see exclude.Profile), the only ones ForcedIncludes can override.
+	Patterns    []exclude.Pattern // Patterns apply before any ignore file, e.g. git's global rules.
+	IgnoreFiles []string          // IgnoreFiles are the ignore file names read in every directory, scoped to that directory's subtree.
+	Overrides   []exclude.Pattern // Overrides apply after every ignore file, e.g. --exclude flags.
+
+	Includes       []exclude.Pattern // Includes is the allowlist of files, disabled when empty.
+	ForcedIncludes []exclude.Pattern // ForcedIncludes are always allowed and win over Defaults, but not over other exclusions.
+
+	MaxFileSize  int64 // MaxFileSize replaces the content of larger files by a placeholder, 0 means no limit.
+	MaxTotalSize int64 // MaxTotalSize caps the content bytes written in total, files that don't fit get a placeholder. 0 means no limit.
+
+	// GeneratedMarkers skip files with a matching line among their first GeneratedLines lines.
+	GeneratedMarkers []*regexp.Regexp
+	GeneratedLines   int
+	// Attributes and the patterns of every AttributeFiles name (read like IgnoreFiles, in this order) skip the files
+	// they match as generated or vendored code, see exclude.LoadAttributePatterns.
+	Attributes     []exclude.Pattern
+	AttributeFiles []string
+
+	// MaxDepth limits how deep the walk goes, 1 being the entries of the root. Directories at that depth are kept
+	// but not entered. 0 means no limit.
+	MaxDepth int
+	// Only, when set, limits the traversal to its files. They still go through every exclusion above.
+	Only *FileSet
+	// Roots, when set, limits the traversal to these slash-separated files and directories below the root.
+	// Files are written once, grouped under a label by the first root holding them. The roots themselves are
+	// never excluded, but what is below a root directory goes through every exclusion above.
+	Roots []string
+	// Files, when not nil, replaces the walk: only these slash-separated files are visited, in walk order, and
+	// listed directories are left out. They go through every exclusion above, including those of the parent
+	// directories the walk would have entered to reach them.
+	Files []string
+	// Lines limits the content written for some files, keyed by their slash-separated path, to these line ranges.
+	// Each range is written as its own entry, headed by the file's path and the lines it covers.
+	Lines map[string][]snippet.Range
+	// ModifiedSince, when not zero, skips files last modified before it.
+	ModifiedSince time.Time
+
+	// Jobs is the number of files read in parallel, values below 2 read one file at a time.
+	Jobs int
+	// Priority patterns write the files they match (or whose parent directories they match) first, in pattern
+	// order. Sort orders the files within each priority group, and files that share a sort key by path.
+	Priority []exclude.Pattern
+	Sort     SortOrder
+
+	// FollowSymlinks walks symlinked directories and reads symlinked files. Otherwise symlinks are listed as
+	// "link -> target" without content.
+	FollowSymlinks bool
+	// ReadTimeout gives up on files that take longer to open and read, 0 means no limit. A streamed content (every
+	// file with one job, files over 1 MB otherwise) is only bounded until its sniffed head is read.
+	ReadTimeout time.Duration
+	// OnError decides whether an unreadable file or directory aborts the traversal (the default) or is skipped.
+	OnError ErrorMode
+	// Warnings receives a line per failure in OnErrorWarn mode, os.Stderr when nil.
+	Warnings io.Writer
+	// Formatter lays out the file entries, output.Text when nil. Beginning and ending the document is up to the caller.
+	Formatter output.Formatter
+}
+
+// Stats counts what TraverseDir did with the entries of the tree.
+type Stats struct {
+	Processed int       // Processed files had their content written, or were listed by ListTree.
+	Skipped   int       // Skipped files and folders were excluded by a pattern.
+	TooDeep   int       // TooDeep folders were at Options.MaxDepth and not entered.
+	Generated int       // Generated files were excluded by a content marker or a generated/vendored attribute.
+	Older     int       // Older files were modified before Options.ModifiedSince.
+	Binary    int       // Binary files were written as a placeholder.
+	TooLarge  int       // TooLarge files exceeded MaxFileSize or MaxTotalSize and were written as a placeholder.
+	Symlinks  int       // Symlinks were listed with their target instead of being followed.
+	Special   int       // Special files (FIFOs, sockets, devices) were written as a placeholder without being opened.
+	TimedOut  int       // TimedOut files took longer than ReadTimeout and were written as a placeholder.
+	Failures  []Failure // Failures are the entries that could not be read, skipped as Options.OnError allows.
+}
+
 // TraverseDir walks root, writes each file via formatter, returns counts.
-func TraverseDir(root string, folderPatterns []string, outputFile io.Writer) (processed, skipped int, err error) {
-	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, e error) error {
-		if e != nil {
-			return e
+func TraverseDir(root string, opts Options, outputFile io.Writer) (stats Stats, err error) {
+	return TraverseTree(DirTree(root), opts, outputFile)
+}
+
+// TraverseTree is TraverseDir for any Tree. Ignore and attribute files are read from the tree itself.
+//
+// The tree is walked first to collect the files to write, which are then ordered by Options.Priority and
+// Options.Sort. Their contents are read by up to Options.Jobs workers and written in that order, so the output
+// doesn't depend on the number of workers.
+func TraverseTree(tree Tree, opts Options, outputFile io.Writer) (stats Stats, err error) {
+	files, err := collectFiles(tree, opts, &stats)
+	if err != nil {
+		return stats, err
+	}
+	sortFiles(files, opts)
+
+	if opts.Formatter == nil {
+		opts.Formatter = output.Text{}
+	}
+	var totalSize int64
+	// withContent gives entry the content data, or a placeholder showing size when data would exceed MaxTotalSize.
+	// It reports whether the content was kept.
+	withContent := func(entry *output.FileEntry, data []byte, size int64) bool {
+		if opts.MaxTotalSize > 0 && totalSize+int64(len(data)) > opts.MaxTotalSize {
+			stats.TooLarge++
+			entry.Note = output.Placeholder(reasonOverTotal, size)
+			return false
+		}
+		totalSize += int64(len(data))
+		entry.Content = data
+		return true
+	}
+	// lineEntries returns an entry per line range of file, "==> path:120-240"
+	lineEntries := func(file walkedFile, data []byte) []output.FileEntry {
+		entries := make([]output.FileEntry, 0, len(file.lines))
+		processed := false
+		for _, lines := range file.lines {
+			part, span, found := snippet.Cut(data, lines)
+			entry := output.FileEntry{Path: file.rel, Detail: ":" + span.String()}
+			switch {
+			case !found:
+				entry.Note = output.Skipped(fmt.Sprintf("past the end of the file, which has %d lines", snippet.LineCount(data)))
+			case opts.MaxFileSize > 0 && int64(len(part)) > opts.MaxFileSize:
+				stats.TooLarge++
+				entry.Note = output.Placeholder(reasonTooLarge, int64(len(part)))
+			default:
+				processed = withContent(&entry, part, int64(len(part))) || processed
+			}
+			entries = append(entries, entry)
 		}
-		rel, _ := filepath.Rel(root, path)
+		if processed {
+			stats.Processed++
+		}
+		return entries
+	}
 
-		if exclude.ShouldExclude(rel, d.Name(), d.IsDir(), folderPatterns) {
-			skipped++
-			if d.IsDir() {
-				return filepath.SkipDir
+	// skipFailure returns failure in OnErrorAbort mode, otherwise it writes its marker in place of file
+	skipFailure := func(file walkedFile, failure error) error {
+		if opts.OnError == "" || opts.OnError == OnErrorAbort {
+			return failure
+		}
+		return reportFailure(&stats, opts, failureOf(file.rel, failure), outputFile)
+	}
+	// streamContent writes entry with the content body copied into the output. Only the size of the file is
+	// known beforehand to check MaxTotalSize, and a Scanner formatter has the content read for it first.
+	streamContent := func(file walkedFile, entry output.FileEntry, body io.Reader) error {
+		size := file.info.Size()
+		if opts.MaxTotalSize > 0 && totalSize+size > opts.MaxTotalSize {
+			stats.TooLarge++
+			entry.Note = output.Placeholder(reasonOverTotal, size)
+			return writeEntries(opts.Formatter, outputFile, entry)
+		}
+		if scanner, ok := opts.Formatter.(output.Scanner); ok {
+			if size <= readAheadSize {
+				// Small enough to hold, rather than to read twice
+				data, err := io.ReadAll(body)
+				if err != nil {
+					return skipFailure(file, &fileError{action: "reading file", name: tree.Name(file.rel), err: err})
+				}
+				if withContent(&entry, data, size) {
+					stats.Processed++
+				}
+				return writeEntries(opts.Formatter, outputFile, entry)
 			}
-			return nil
+			if err := scanFile(tree, file.rel, scanner); err != nil {
+				return skipFailure(file, err)
+			}
+		}
+
+		totalSize += size
+		opts.Formatter.BeginFile(outputFile, entry)
+		source := &sourceReader{Reader: body}
+		if _, err := io.Copy(outputFile, source); err != nil {
+			if source.err == nil {
+				return fmt.Errorf("❌🪲  [ERROR] failed to write to output: %w", err)
+			}
+			failure := &fileError{action: "reading file", name: tree.Name(file.rel), err: source.err}
+			if opts.OnError == "" || opts.OnError == OnErrorAbort {
+				return failure
+			}
+			// The entry is already partly written, the failure is only recorded
+			recordFailure(&stats, opts, failureOf(file.rel, failure))
+		} else {
+			stats.Processed++
 		}
-		if d.IsDir() {
+		opts.Formatter.EndFile(outputFile)
+		return nil
+	}
+
+	roots, lastRoot := cleanRoots(opts.Roots), -1
+	err = readFiles(tree, files, opts, func(file walkedFile, c fileContent) error {
+		defer c.close()
+		if c.generated {
+			stats.Generated++
 			return nil
 		}
+		if file.root >= 0 && file.root != lastRoot {
+			opts.Formatter.BeginRoot(outputFile, roots.label(file))
+			lastRoot = file.root
+		}
+		if failure := cmp.Or(file.failure, c.err); failure != nil {
+			return skipFailure(file, failure)
+		}
+
+		entry := output.FileEntry{Path: file.rel}
+		switch {
+		case file.link:
+			stats.Symlinks++
+			entry.Target = file.target
+			if file.linkNote != "" {
+				entry.Detail = fmt.Sprintf(" [%s]", file.linkNote)
+			}
+		case file.special != "":
+			stats.Special++
+			entry.Note = output.Skipped(file.special)
+		case c.timedOut:
+			stats.TimedOut++
+			entry.Note = output.Skipped(fmt.Sprintf("read timed out after %s", opts.ReadTimeout))
+		case file.tooLarge:
+			stats.TooLarge++
+			entry.Note = output.Placeholder(reasonTooLarge, file.info.Size())
+		case c.binary:
+			stats.Binary++
+			entry.Note = output.Placeholder(reasonBinary, file.info.Size())
+		case len(file.lines) > 0:
+			return writeEntries(opts.Formatter, outputFile, lineEntries(file, c.data)...)
+		case c.body != nil:
+			return streamContent(file, entry, c.body)
+		default:
+			if withContent(&entry, c.data, file.info.Size()) {
+				stats.Processed++
+			}
+		}
+		return writeEntries(opts.Formatter, outputFile, entry)
+	})
+	return stats, err
+}
+
+// writeEntries writes file entries with formatter, their content unless they have a note instead.
+func writeEntries(formatter output.Formatter, outputFile io.Writer, entries ...output.FileEntry) error {
+	for _, entry := range entries {
+		formatter.BeginFile(outputFile, entry)
+		if entry.Note == "" && entry.Target == "" {
+			if _, err := outputFile.Write(entry.Content); err != nil {
+				return fmt.Errorf("❌🪲  [ERROR] failed to write to output: %w", err)
+			}
+		}
+		formatter.EndFile(outputFile)
+	}
+	return nil
+}
+
+// scanFile has scanner read the content of the file rel, before it is streamed.
+func scanFile(tree Tree, rel string, scanner output.Scanner) error {
+	openedFile, err := tree.FS.Open(rel)
+	if err != nil {
+		return &fileError{action: "opening file", name: tree.Name(rel), err: err}
+	}
+	defer closeFile(openedFile)
+	if err := scanner.Scan(openedFile); err != nil {
+		return &fileError{action: "reading file", name: tree.Name(rel), err: err}
+	}
+	return nil
+}
+
+// sourceReader tells the read errors of a copy apart from its write errors.
+type sourceReader struct {
+	io.Reader
+	err error
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// reportFailure records a skipped failure and writes its marker.
+func reportFailure(stats *Stats, opts Options, failure Failure, outputFile io.Writer) error {
+	recordFailure(stats, opts, failure)
+	return writeEntries(opts.Formatter, outputFile, output.FileEntry{Path: failure.Path, Note: output.Skipped("error " + failure.Reason)})
+}
+
+// recordFailure adds a skipped failure to stats and warns about it in OnErrorWarn mode.
+func recordFailure(stats *Stats, opts Options, failure Failure) {
+	stats.Failures = append(stats.Failures, failure)
+	if opts.OnError == OnErrorWarn {
+		warnings := opts.Warnings
+		if warnings == nil {
+			warnings = os.Stderr
+		}
+		_, _ = fmt.Fprintf(warnings, "⚠️  %s\n", failure)
+	}
+}
+
+// Explain reports whether TraverseDir(root, opts) would skip the slash-separated rel and which pattern decided it.
+// The ignore files of every directory between root and rel are read, just as the walk would read them.
+func Explain(root, rel string, isDir bool, opts Options) (exclude.Match, error) {
+	rules := newRuleSet(DirTree(root), opts)
+	rel = path.Clean(filepath.ToSlash(rel))
+
+	dir := "."
+	for _, segment := range strings.Split(rel, "/") {
+		if dir != "." {
+			if match := rules.match(dir, true); match.Excluded {
+				match.Rule = exclude.RuleParentDir
+				return match, nil
+			}
+		}
+		if err := rules.loadIgnoreFiles(dir); err != nil {
+			return exclude.Match{}, err
+		}
+		dir = path.Join(dir, segment)
+	}
+	return rules.match(rel, isDir), nil
+}
+
+// ruleSet keeps the exclusion patterns of a traversal split by precedence level, so ignore files found
+// deep in the tree still rank below the patterns that must override them. Every level is a compiled matcher.
+type ruleSet struct {
+	tree     Tree
+	opts     Options
+	levels   []*exclude.Matcher // levels[0] is Defaults, levels[1] Patterns, then the ignore files, levels[len-1] Overrides.
+	includes *exclude.Matcher
+	forced   *exclude.Matcher
+	// attributes holds Options.Attributes and the patterns of the attribute files read so far.
+	attributes *exclude.Matcher
+	// forcedDirs are the directories only Defaults exclude, entered anyway for the ForcedIncludes that could match
+	// below them. They map to the exclusion that everything else inside them gets.
+	forcedDirs map[string]exclude.Match
+}
+
+func newRuleSet(tree Tree, opts Options) *ruleSet {
+	levels := make([]*exclude.Matcher, len(opts.IgnoreFiles)+3)
+	for i := range levels {
+		levels[i] = exclude.NewMatcher(nil)
+	}
+	levels[0].Add(opts.Defaults...)
+	levels[1].Add(opts.Patterns...)
+	levels[len(levels)-1].Add(opts.Overrides...)
+
+	return &ruleSet{
+		tree:     tree,
+		opts:     opts,
+		levels:   levels,
+		includes: exclude.NewMatcher(opts.Includes),
+		forced:   exclude.NewMatcher(opts.ForcedIncludes),
+
+		attributes: exclude.NewMatcher(opts.Attributes),
+		forcedDirs: map[string]exclude.Match{},
+	}
+}
+
+// match decides whether rel is skipped. Exclusion levels are evaluated from the highest precedence down and the
+// first level with a matching pattern decides; ForcedIncludes then win over Defaults, and files must pass Includes.
+//
+// A directory only Defaults exclude is still entered when a forced include could match below it, such as
+// "+vendor/foo/x.go" for "vendor/", and everything inside it that no forced include matches is skipped.
+// Directories are matched before their content, as the walk does.
+func (r *ruleSet) match(rel string, isDir bool) exclude.Match {
+	if rel == "." {
+		return exclude.Match{Path: rel}
+	}
+
+	match, byDefaults := exclude.NewMatch(nil, rel), false
+	for i := len(r.levels) - 1; i >= 0; i-- {
+		if last := r.levels[i].LastMatch(rel, isDir); last != nil {
+			match = exclude.NewMatch(last, rel)
+			if match.Excluded && i == 0 {
+				match, byDefaults = r.forcedMatch(rel, isDir, match)
+			}
+			break
+		}
+	}
+	if parent, inside := r.forcedDirs[path.Dir(rel)]; inside && !match.Excluded && match.Rule != exclude.RuleForcedInclude {
+		match, byDefaults = r.forcedMatch(rel, isDir, parent)
+	}
+	if match.Excluded && byDefaults && isDir {
+		if forced := r.forced.MayMatchBelow(rel); forced != nil {
+			match.Rule = exclude.RuleParentDir
+			r.forcedDirs[rel] = match
+			return exclude.Match{Pattern: forced, Rule: exclude.RuleForcedInclude, Path: rel}
+		}
+	}
+	if match.Excluded || isDir || r.includes.Len() == 0 {
+		return match
+	}
 
-		processed++
-		output.WriteHeader(outputFile, rel)
+	// Allowlist: the file must be matched by an include or a forced include
+	if included := r.includes.IncludeMatch(rel, false); included != nil && !included.Negate {
+		return match
+	}
+	if forced := r.forced.IncludeMatch(rel, false); forced != nil && !forced.Negate {
+		return match
+	}
+	return exclude.Match{Excluded: true, Rule: exclude.RuleNotIncluded, Path: rel}
+}
 
-		openedFile, err := os.Open(path)
+// forcedMatch returns the forced include matching rel, or else the exclusion of Defaults that applies to it.
+// byDefaults reports the latter.
+func (r *ruleSet) forcedMatch(rel string, isDir bool, defaults exclude.Match) (match exclude.Match, byDefaults bool) {
+	if forced := r.forced.IncludeMatch(rel, isDir); forced != nil && !forced.Negate {
+		return exclude.Match{Pattern: forced, Rule: exclude.RuleForcedInclude, Path: rel}, false
+	}
+	return defaults, true
+}
+
+// loadIgnoreFiles reads the ignore files of the directory rel and scopes their patterns to it.
+func (r *ruleSet) loadIgnoreFiles(rel string) error {
+	for i, name := range r.opts.IgnoreFiles {
+		patterns, err := exclude.LoadPatternFileFS(r.tree.FS, path.Join(rel, name))
 		if err != nil {
-			return fmt.Errorf("❌🪲  [ERROR] error opening file %v: %v", path, err)
+			return err
 		}
-		defer utils.SafeCloseFile(openedFile)
+		r.levels[i+2].Add(exclude.ScopePatterns(r.tree.withNames(patterns), rel)...)
+	}
+	return nil
+}
 
-		_, _ = io.Copy(outputFile, openedFile)
-		output.WriteSeparator(outputFile)
-		return nil
-	})
-	return
+// loadAttributeFiles reads the attribute files of the directory rel and scopes their patterns to it.
+func (r *ruleSet) loadAttributeFiles(rel string) error {
+	for _, name := range r.opts.AttributeFiles {
+		patterns, err := exclude.LoadAttributePatternsFS(r.tree.FS, path.Join(rel, name), exclude.GeneratedAttributes)
+		if err != nil {
+			return err
+		}
+		r.attributes.Add(exclude.ScopePatterns(r.tree.withNames(patterns), rel)...)
+	}
+	return nil
+}
+
+// generated reports whether the file rel is marked as generated or vendored by its attributes.
+func (r *ruleSet) generated(rel string) bool {
+	last := r.attributes.LastMatch(rel, false)
+	return last != nil && !last.Negate
 }
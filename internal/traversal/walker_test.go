@@ -0,0 +1,118 @@
+package traversal
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/seyedali-dev/treeclip/internal/exclude"
+)
+
+// writtenPaths traverses fsys with opts and returns the paths of the "==> path" headers written.
+func writtenPaths(t *testing.T, fsys fstest.MapFS, opts Options) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if _, err := TraverseTree(FSTree(fsys, "/src"), opts, &out); err != nil {
+		t.Fatalf("TraverseTree: %v", err)
+	}
+	var paths []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if rel, found := strings.CutPrefix(line, "==> "); found {
+			paths = append(paths, rel)
+		}
+	}
+	return paths
+}
+
+// ruleOptions parses the default exclusions and the include patterns ("+" ones forced) of a test.
+func ruleOptions(t *testing.T, defaults, includes []string) Options {
+	t.Helper()
+	defaultPatterns, err := exclude.ParsePatterns(defaults, exclude.DefaultSource("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	includePatterns, forced, err := exclude.ParseIncludePatterns(includes, exclude.FlagSource("--include"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Options{
+		Defaults:       defaultPatterns,
+		IgnoreFiles:    []string{exclude.GitIgnoreFileName},
+		Includes:       includePatterns,
+		ForcedIncludes: forced,
+	}
+}
+
+func mapFile(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+// TestForcedIncludeInsideDefaultExcludedDir checks that "+vendor/foo/x.go" reaches into vendor/, excluded by the
+// defaults, while the rest of vendor/ stays excluded.
+func TestForcedIncludeInsideDefaultExcludedDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":          mapFile("package main\n"),
+		"vendor/foo/x.go":  mapFile("package foo\n"),
+		"vendor/foo/y.go":  mapFile("package foo\n"),
+		"vendor/bar/z.go":  mapFile("package bar\n"),
+		"vendor/modules.x": mapFile("# modules\n"),
+	}
+	got := writtenPaths(t, fsys, ruleOptions(t, []string{"vendor/"}, []string{"+vendor/foo/x.go"}))
+	if want := []string{"main.go", "vendor/foo/x.go"}; !slices.Equal(got, want) {
+		t.Errorf("written paths = %q, want %q", got, want)
+	}
+}
+
+// TestForcedIncludeKeepsGitignore checks that a forced include only overrides the defaults, not .gitignore.
+func TestForcedIncludeKeepsGitignore(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":      mapFile("api.gen.go\nvendor/\n"),
+		"main.go":         mapFile("package main\n"),
+		"api.gen.go":      mapFile("package main\n"),
+		"vendor/foo/x.go": mapFile("package foo\n"),
+	}
+	got := writtenPaths(t, fsys, ruleOptions(t, []string{"vendor/", "*.gen.go"}, []string{"+api.gen.go", "+vendor/foo/x.go"}))
+	if want := []string{".gitignore", "main.go"}; !slices.Equal(got, want) {
+		t.Errorf("written paths = %q, want %q", got, want)
+	}
+}
+
+// TestIncludeDirAllowsEverythingBelow checks that "-i internal/" allows every file below internal/ and only those.
+func TestIncludeDirAllowsEverythingBelow(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.md":               mapFile("# readme\n"),
+		"main.go":                 mapFile("package main\n"),
+		"internal/a.go":           mapFile("package internal\n"),
+		"internal/deep/b.txt":     mapFile("b\n"),
+		"internal/deep/er/c.json": mapFile("{}\n"),
+		"other/internal.go":       mapFile("package other\n"),
+	}
+	got := writtenPaths(t, fsys, ruleOptions(t, nil, []string{"internal/"}))
+	if want := []string{"internal/a.go", "internal/deep/b.txt", "internal/deep/er/c.json"}; !slices.Equal(got, want) {
+		t.Errorf("written paths = %q, want %q", got, want)
+	}
+}
+
+// TestPrecedenceLevels checks that ignore files override the defaults, and --exclude flags override ignore files.
+func TestPrecedenceLevels(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     mapFile("!keep.log\nsecret.txt\n"),
+		"app.log":        mapFile("log\n"),
+		"keep.log":       mapFile("log\n"),
+		"secret.txt":     mapFile("secret\n"),
+		"notes.txt":      mapFile("notes\n"),
+		"sub/.gitignore": mapFile("!secret.txt\n"),
+		"sub/secret.txt": mapFile("secret\n"),
+	}
+	opts := ruleOptions(t, []string{"*.log"}, nil)
+	var err error
+	if opts.Overrides, err = exclude.ParsePatterns([]string{"notes.txt"}, exclude.FlagSource("--exclude")); err != nil {
+		t.Fatal(err)
+	}
+	got := writtenPaths(t, fsys, opts)
+	if want := []string{".gitignore", "keep.log", "sub/.gitignore", "sub/secret.txt"}; !slices.Equal(got, want) {
+		t.Errorf("written paths = %q, want %q", got, want)
+	}
+}
@@ -15,7 +15,7 @@ func SafeCloseFile(file *os.File) {
 
 // WriteData writes the provided data to the end of file.
 func WriteData(file *os.File, data string) {
-	if _, err := fmt.Fprintf(file, data); err != nil {
+	if _, err := fmt.Fprint(file, data); err != nil {
 		panic(fmt.Sprintf("❌🪲  [ERROR] failed to write data to file %s: %v (╯°□°）╯︵ ┻━┻", file.Name(), err))
 	}
 }
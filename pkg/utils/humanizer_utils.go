@@ -3,6 +3,7 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -37,3 +38,27 @@ func FormatNumber(n int) string {
 	}
 	return result.String()
 }
+
+// ParseBytes parses a human-readable size such as "512", "200KB", "1.5 MB" or "2g" into bytes, using the same
+// 1024-based units as FormatBytes.
+func ParseBytes(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	s = strings.TrimSuffix(s, "B")
+	s = strings.TrimSuffix(s, "I") // KiB, MiB, ...
+
+	multiplier := int64(1)
+	if s != "" {
+		if exp := strings.IndexByte("KMGTPE", s[len(s)-1]); exp >= 0 {
+			for range exp + 1 {
+				multiplier *= 1024
+			}
+			s = s[:len(s)-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected e.g. 512KB or 1.5MB (ノಠ益ಠ)ノ", size)
+	}
+	return int64(value * float64(multiplier)), nil
+}